@@ -6,7 +6,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 // Version information, passed from main package
@@ -16,6 +18,42 @@ var (
 	date    = "unknown"
 )
 
+// Output formats
+const (
+	// OutputText prints human-readable messages
+	OutputText = "text"
+	// OutputJSON prints a Claude Code hook JSON response on stdout
+	OutputJSON = "json"
+)
+
+// Hook events ccnewline can run as
+const (
+	// EventPostToolUse fixes files after a tool has written them
+	EventPostToolUse = "posttooluse"
+	// EventPreToolUse fixes the tool input before the tool writes it
+	EventPreToolUse = "pretooluse"
+	// EventStop fixes every file written during the session when Claude or a subagent stops
+	EventStop = "stop"
+)
+
+// ProjectDirEnv is the environment variable Claude Code sets to the project root for
+// hook commands, telling hook runs apart from manual ones
+const ProjectDirEnv = "CLAUDE_PROJECT_DIR"
+
+// sizeUnits maps size suffixes to their multipliers
+var sizeUnits = map[string]int64{
+	"":    1,
+	"k":   1 << 10,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+}
+
 // Config holds the configuration options for the tool
 type Config struct {
 	// Debug enables detailed processing information output
@@ -28,6 +66,30 @@ type Config struct {
 	// Include contains glob patterns for files to include in processing
 	// Mutually exclusive with Exclude
 	Include []string
+	// Output selects the output format (text or json)
+	Output string
+	// Tools lists the tool names to act on; when empty every tool
+	// except read-only ones is acted on
+	Tools []string
+	// KeepNotebookCells disables normalization of trailing newlines in notebook cell sources
+	KeepNotebookCells bool
+	// Enforce reports files that need fixing to Claude instead of modifying them
+	Enforce bool
+	// Strict accepts only hook JSON input, failing instead of treating other input as paths
+	Strict bool
+	// PathsFromStdin reads stdin as a list of file paths instead of hook JSON
+	PathsFromStdin bool
+	// NullSeparated splits the path list on NUL bytes; it implies PathsFromStdin
+	NullSeparated bool
+	// MaxInputSize caps the size of the input in bytes; zero disables the cap
+	MaxInputSize int64
+	// Event selects the hook event ccnewline runs as (posttooluse, pretooluse or stop)
+	Event string
+	// AskPermission makes PreToolUse corrections prompt for permission unless the session
+	// approves edits automatically, instead of leaving the decision to Claude Code
+	AskPermission bool
+	// Policies holds the fixing policy loaded from the --config file
+	Policies PolicySet
 }
 
 // IsDebugMode returns whether debug mode is enabled
@@ -36,8 +98,15 @@ func (c *Config) IsDebugMode() bool {
 }
 
 // IsSilent returns whether silent mode is enabled
+// A hook response implies silent mode since stdout is reserved for it
 func (c *Config) IsSilent() bool {
-	return c.Silent
+	return c.Silent || c.UsesHookResponse()
+}
+
+// UsesHookResponse returns whether stdout carries a JSON hook response
+// PreToolUse mode always responds in JSON since that is how the input is updated
+func (c *Config) UsesHookResponse() bool {
+	return c.Output == OutputJSON || c.Event == EventPreToolUse
 }
 
 // versionHandler handles version display functionality
@@ -58,6 +127,22 @@ func (av *argumentValidator) validateArgs(config *Config) {
 		fmt.Fprintf(os.Stderr, "Error: --exclude and --include are mutually exclusive\n")
 		os.Exit(1)
 	}
+	switch config.Output {
+	case "", OutputText, OutputJSON:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --output must be %q or %q\n", OutputText, OutputJSON)
+		os.Exit(1)
+	}
+	if config.Enforce && config.Event == EventPreToolUse {
+		fmt.Fprintf(os.Stderr, "Error: --enforce cannot be used with --event %s\n", EventPreToolUse)
+		os.Exit(1)
+	}
+	switch config.Event {
+	case "", EventPostToolUse, EventPreToolUse, EventStop:
+	default:
+		fmt.Fprintf(os.Stderr, "Error: --event must be %q, %q or %q\n", EventPostToolUse, EventPreToolUse, EventStop)
+		os.Exit(1)
+	}
 }
 
 // flagParser handles command-line flag parsing
@@ -80,7 +165,7 @@ func newFlagParser() *flagParser {
 func (fp *flagParser) parse() *Config {
 	var config Config
 	var showVersion bool
-	var excludeStr, includeStr string
+	var excludeStr, includeStr, toolsStr, maxInputSizeStr, configPath string
 
 	fp.flagSet.Usage = usage
 	defineBoolFlag(fp.flagSet, &config.Debug, "debug", "d", false, "Enable debug output")
@@ -88,6 +173,17 @@ func (fp *flagParser) parse() *Config {
 	defineBoolFlag(fp.flagSet, &showVersion, "version", "v", false, "Show version information")
 	defineStringFlag(fp.flagSet, &excludeStr, "exclude", "e", "", "Exclude files matching glob patterns (comma-separated)")
 	defineStringFlag(fp.flagSet, &includeStr, "include", "i", "", "Include only files matching glob patterns (comma-separated)")
+	defineStringFlag(fp.flagSet, &config.Output, "output", "o", OutputText, "Output format: text or json")
+	defineStringFlag(fp.flagSet, &toolsStr, "tools", "t", "", "Act only on these tool names (comma-separated)")
+	defineBoolFlag(fp.flagSet, &config.KeepNotebookCells, "keep-notebook-cells", "", false, "Do not normalize notebook cell sources")
+	defineStringFlag(fp.flagSet, &config.Event, "event", "", EventPostToolUse, "Hook event to run as: posttooluse, pretooluse or stop")
+	defineBoolFlag(fp.flagSet, &config.AskPermission, "ask-permission", "", false, "Prompt for permission for corrected PreToolUse calls")
+	defineBoolFlag(fp.flagSet, &config.Strict, "strict", "", os.Getenv(ProjectDirEnv) != "", "Accept only hook JSON input (default when run as a hook)")
+	defineBoolFlag(fp.flagSet, &config.PathsFromStdin, "paths-from-stdin", "", false, "Read stdin as newline-separated file paths")
+	defineBoolFlag(fp.flagSet, &config.NullSeparated, "null", "0", false, "Read stdin as NUL-separated file paths")
+	defineStringFlag(fp.flagSet, &maxInputSizeStr, "max-input-size", "", "64MiB", "Maximum input size, such as 512KiB or 64MiB (0 for no limit)")
+	defineBoolFlag(fp.flagSet, &config.Enforce, "enforce", "", false, "Report files that need fixing to Claude instead of modifying them")
+	defineStringFlag(fp.flagSet, &configPath, "config", "c", "", "JSON policy file with per-pattern rules")
 
 	var showHelp bool
 	defineBoolFlag(fp.flagSet, &showHelp, "help", "h", false, "Show this help message")
@@ -109,6 +205,26 @@ func (fp *flagParser) parse() *Config {
 	if includeStr != "" {
 		config.Include = parsePatterns(includeStr)
 	}
+	if toolsStr != "" {
+		config.Tools = parsePatterns(toolsStr)
+	}
+	maxInputSize, err := parseSize(maxInputSizeStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --max-input-size: %v\n", err)
+		os.Exit(1)
+	}
+	config.MaxInputSize = maxInputSize
+	if config.NullSeparated {
+		config.PathsFromStdin = true
+	}
+	if configPath != "" {
+		policies, err := loadPolicySet(configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --config: %v\n", err)
+			os.Exit(1)
+		}
+		config.Policies = policies
+	}
 
 	fp.validator.validateArgs(&config)
 	return &config
@@ -128,6 +244,24 @@ Options:
   -h, --help       Show this help message
   -e, --exclude    Exclude files matching glob patterns (comma-separated)
   -i, --include    Include only files matching glob patterns (comma-separated)
+  -o, --output     Output format: text (default) or json (hook response for Claude)
+  -t, --tools      Act only on these tool names (comma-separated, default: all but read-only tools)
+      --keep-notebook-cells
+                   Do not normalize trailing newlines in notebook (.ipynb) cell sources
+      --event      Hook event to run as: posttooluse (default), pretooluse or stop
+      --ask-permission
+                   Prompt for permission for calls corrected in PreToolUse mode, even when
+                   permission rules would allow them (except in acceptEdits and bypassPermissions modes)
+      --enforce    Do not modify files; report what needs fixing on stderr and exit with code 2
+      --strict     Accept only hook JSON input and fail on anything else, instead of
+                   reading input that is not JSON as file paths
+                   (default when run as a hook, i.e. CLAUDE_PROJECT_DIR is set)
+      --paths-from-stdin
+                   Read stdin as newline-separated file paths instead of hook JSON
+  -0, --null       Read stdin as NUL-separated file paths (implies --paths-from-stdin)
+      --max-input-size
+                   Maximum input size, such as 512KiB or 64MiB (default 64MiB, 0 for no limit)
+  -c, --config     JSON policy file setting how files are fixed, with per-pattern rules
 `, os.Args[0])
 }
 
@@ -161,6 +295,21 @@ func parsePatterns(patterns string) []string {
 	return result
 }
 
+// parseSize parses a byte size with an optional binary unit suffix, such as 512KiB or 64M
+func parseSize(size string) (int64, error) {
+	size = strings.TrimSpace(size)
+	digits := strings.TrimRightFunc(size, unicode.IsLetter)
+	multiplier, ok := sizeUnits[strings.ToLower(size[len(digits):])]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit in %q", size)
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(digits), 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return value * multiplier, nil
+}
+
 // ParseFlags processes command-line arguments and returns configuration
 func ParseFlags(v, c, d string) *Config {
 	version = v
@@ -274,6 +274,13 @@ func TestParseFlagsWithExcludeInclude(t *testing.T) {
 				Include: nil,
 			},
 		},
+		{
+			name: "tools list",
+			args: []string{"--tools", "Write, Edit"},
+			expected: &Config{
+				Tools: []string{"Write", "Edit"},
+			},
+		},
 		{
 			name: "combined flags",
 			args: []string{"-d", "-s", "--include", "*.go"},
@@ -317,6 +324,17 @@ func TestParseFlagsWithExcludeInclude(t *testing.T) {
 				}
 			}
 
+			// Check tools
+			if len(result.Tools) != len(tt.expected.Tools) {
+				t.Errorf("Tools length = %v, want %v", len(result.Tools), len(tt.expected.Tools))
+			} else {
+				for i, tool := range result.Tools {
+					if tool != tt.expected.Tools[i] {
+						t.Errorf("Tools[%d] = %v, want %v", i, tool, tt.expected.Tools[i])
+					}
+				}
+			}
+
 			// Check include patterns
 			if len(result.Include) != len(tt.expected.Include) {
 				t.Errorf("Include length = %v, want %v", len(result.Include), len(tt.expected.Include))
@@ -407,3 +425,219 @@ func TestParseFlagsFunction(t *testing.T) {
 		t.Error("Debug flag should be set")
 	}
 }
+
+func TestParseFlagsOutput(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectOutput string
+		expectEvent  string
+		expectSilent bool
+	}{
+		{
+			name:         "default output",
+			args:         []string{},
+			expectOutput: OutputText,
+			expectEvent:  EventPostToolUse,
+			expectSilent: false,
+		},
+		{
+			name:         "json output",
+			args:         []string{"--output", "json"},
+			expectOutput: OutputJSON,
+			expectEvent:  EventPostToolUse,
+			expectSilent: true,
+		},
+		{
+			name:         "json output shorthand",
+			args:         []string{"-o", "json"},
+			expectOutput: OutputJSON,
+			expectEvent:  EventPostToolUse,
+			expectSilent: true,
+		},
+		{
+			name:         "pre tool use event",
+			args:         []string{"--event", "pretooluse"},
+			expectOutput: OutputText,
+			expectEvent:  EventPreToolUse,
+			expectSilent: true,
+		},
+		{
+			name:         "stop event",
+			args:         []string{"--event", "stop"},
+			expectOutput: OutputText,
+			expectEvent:  EventStop,
+			expectSilent: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			os.Args = append([]string{"test"}, tt.args...)
+
+			parser := newFlagParser()
+			result := parser.parse()
+
+			if result.Output != tt.expectOutput {
+				t.Errorf("Output = %v, want %v", result.Output, tt.expectOutput)
+			}
+			if result.Event != tt.expectEvent {
+				t.Errorf("Event = %v, want %v", result.Event, tt.expectEvent)
+			}
+			if result.IsSilent() != tt.expectSilent {
+				t.Errorf("IsSilent() = %v, want %v", result.IsSilent(), tt.expectSilent)
+			}
+		})
+	}
+}
+
+func TestParseFlagsEnforce(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		expectEnforce bool
+	}{
+		{name: "default", args: []string{}, expectEnforce: false},
+		{name: "enforce", args: []string{"--enforce"}, expectEnforce: true},
+		{name: "enforce on stop", args: []string{"--enforce", "--event", "stop"}, expectEnforce: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			os.Args = append([]string{"test"}, tt.args...)
+
+			result := newFlagParser().parse()
+			if result.Enforce != tt.expectEnforce {
+				t.Errorf("Enforce = %v, want %v", result.Enforce, tt.expectEnforce)
+			}
+		})
+	}
+}
+
+func TestParseFlagsAskPermission(t *testing.T) {
+	tests := []struct {
+		name                string
+		args                []string
+		expectAskPermission bool
+	}{
+		{name: "default", args: []string{"--event", "pretooluse"}, expectAskPermission: false},
+		{name: "ask permission", args: []string{"--event", "pretooluse", "--ask-permission"}, expectAskPermission: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			os.Args = append([]string{"test"}, tt.args...)
+
+			result := newFlagParser().parse()
+			if result.AskPermission != tt.expectAskPermission {
+				t.Errorf("AskPermission = %v, want %v", result.AskPermission, tt.expectAskPermission)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  int64
+		expectErr bool
+	}{
+		{input: "0", expected: 0},
+		{input: "1048576", expected: 1 << 20},
+		{input: "512KiB", expected: 512 << 10},
+		{input: "64MiB", expected: 64 << 20},
+		{input: "64mb", expected: 64 << 20},
+		{input: "2G", expected: 2 << 30},
+		{input: "10 MiB", expected: 10 << 20},
+		{input: "", expectErr: true},
+		{input: "MiB", expectErr: true},
+		{input: "10TB", expectErr: true},
+		{input: "-1", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseSize(tt.input)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("parseSize(%q) error = %v, expectErr %v", tt.input, err, tt.expectErr)
+			}
+			if result != tt.expected {
+				t.Errorf("parseSize(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseFlagsMaxInputSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected int64
+	}{
+		{name: "default", args: []string{}, expected: 64 << 20},
+		{name: "custom", args: []string{"--max-input-size", "1MiB"}, expected: 1 << 20},
+		{name: "no limit", args: []string{"--max-input-size", "0"}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			os.Args = append([]string{"test"}, tt.args...)
+
+			result := newFlagParser().parse()
+			if result.MaxInputSize != tt.expected {
+				t.Errorf("MaxInputSize = %v, want %v", result.MaxInputSize, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseFlagsInputMode(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		hookEnv        string
+		expectStrict   bool
+		expectPaths    bool
+		expectNullSeps bool
+	}{
+		{name: "manual run", args: []string{}, expectStrict: false},
+		{name: "hook run", args: []string{}, hookEnv: "/project", expectStrict: true},
+		{name: "hook run with strict disabled", args: []string{"--strict=false"}, hookEnv: "/project", expectStrict: false},
+		{name: "strict flag", args: []string{"--strict"}, expectStrict: true},
+		{name: "paths from stdin", args: []string{"--paths-from-stdin"}, expectPaths: true},
+		{name: "NUL-separated paths", args: []string{"-0"}, expectPaths: true, expectNullSeps: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(ProjectDirEnv, tt.hookEnv)
+			oldArgs := os.Args
+			defer func() { os.Args = oldArgs }()
+
+			os.Args = append([]string{"test"}, tt.args...)
+
+			result := newFlagParser().parse()
+			if result.Strict != tt.expectStrict {
+				t.Errorf("Strict = %v, want %v", result.Strict, tt.expectStrict)
+			}
+			if result.PathsFromStdin != tt.expectPaths {
+				t.Errorf("PathsFromStdin = %v, want %v", result.PathsFromStdin, tt.expectPaths)
+			}
+			if result.NullSeparated != tt.expectNullSeps {
+				t.Errorf("NullSeparated = %v, want %v", result.NullSeparated, tt.expectNullSeps)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Final newline policies
+const (
+	// FinalNewlineEnsure appends a line ending when the file does not end with one
+	FinalNewlineEnsure = "ensure"
+	// FinalNewlineExactlyOne also collapses trailing blank lines into a single line ending
+	FinalNewlineExactlyOne = "exactly-one"
+	// FinalNewlineNone removes every line ending at the end of the file
+	FinalNewlineNone = "none"
+)
+
+// finalNewlinePolicies lists the accepted values of final_newline
+var finalNewlinePolicies = []string{FinalNewlineEnsure, FinalNewlineExactlyOne, FinalNewlineNone}
+
+// Byte order mark policies
+const (
+	// BOMPreserve leaves the UTF-8 byte order mark as it is
+	BOMPreserve = "preserve"
+	// BOMStrip removes a UTF-8 byte order mark
+	BOMStrip = "strip"
+	// BOMRequire adds a UTF-8 byte order mark when the file lacks one
+	BOMRequire = "require"
+)
+
+// bomPolicies lists the accepted values of bom
+var bomPolicies = []string{BOMPreserve, BOMStrip, BOMRequire}
+
+// Line ending policies
+const (
+	// EndOfLineLF converts every line ending to LF
+	EndOfLineLF = "lf"
+	// EndOfLineCRLF converts every line ending to CRLF
+	EndOfLineCRLF = "crlf"
+	// EndOfLinePreserveDominant converts every line ending to the one the file uses most
+	EndOfLinePreserveDominant = "preserve-dominant"
+)
+
+// endOfLinePolicies lists the accepted values of end_of_line
+var endOfLinePolicies = []string{EndOfLineLF, EndOfLineCRLF, EndOfLinePreserveDominant}
+
+// Control character policies
+const (
+	// ControlCharactersStrip removes ANSI escape sequences and stray control characters
+	ControlCharactersStrip = "strip"
+	// ControlCharactersReport reports ANSI escape sequences and stray control characters
+	// without changing the file
+	ControlCharactersReport = "report"
+)
+
+// controlCharacterPolicies lists the accepted values of control_characters
+var controlCharacterPolicies = []string{ControlCharactersStrip, ControlCharactersReport}
+
+// Legacy charsets files can be declared to be in
+const (
+	// CharsetShiftJIS is the Shift_JIS encoding of Japanese
+	CharsetShiftJIS = "shift_jis"
+	// CharsetEUCJP is the EUC-JP encoding of Japanese
+	CharsetEUCJP = "euc-jp"
+	// CharsetLatin1 is ISO 8859-1
+	CharsetLatin1 = "latin-1"
+)
+
+// charsets lists the accepted values of charset
+var charsets = []string{CharsetShiftJIS, CharsetEUCJP, CharsetLatin1}
+
+// Indentation styles
+const (
+	// IndentStyleTab indents with tabs
+	IndentStyleTab = "tab"
+	// IndentStyleSpace indents with spaces
+	IndentStyleSpace = "space"
+)
+
+// indentStyles lists the accepted values of indent_style
+var indentStyles = []string{IndentStyleTab, IndentStyleSpace}
+
+// maxIndentSize is the largest accepted indent_size
+const maxIndentSize = 16
+
+// Policy describes how files are fixed. Empty fields inherit from the
+// policy they refine.
+type Policy struct {
+	// FinalNewline selects how the end of the file is fixed (ensure, exactly-one or none)
+	FinalNewline string `json:"final_newline,omitempty"`
+	// TrimTrailingWhitespace removes spaces and tabs at the end of lines when true
+	TrimTrailingWhitespace *bool `json:"trim_trailing_whitespace,omitempty"`
+	// BOM selects what happens to a UTF-8 byte order mark (preserve, strip or require)
+	BOM string `json:"bom,omitempty"`
+	// EndOfLine normalizes every line ending of the file (lf, crlf or preserve-dominant);
+	// empty leaves mixed line endings alone
+	EndOfLine string `json:"end_of_line,omitempty"`
+	// IndentStyle converts leading indentation to tabs or spaces (tab or space);
+	// empty leaves indentation alone
+	IndentStyle string `json:"indent_style,omitempty"`
+	// IndentSize is the number of columns of an indentation level and of a tab;
+	// zero uses the default
+	IndentSize int `json:"indent_size,omitempty"`
+	// UnicodeCleanup removes invisible characters and reports confusable ones when true
+	UnicodeCleanup *bool `json:"unicode_cleanup,omitempty"`
+	// AllowTypographic keeps typographic quotes, dashes and ellipses out of the
+	// unicode_cleanup report when true, for prose
+	AllowTypographic *bool `json:"allow_typographic,omitempty"`
+	// TrimLeadingBlankLines removes the blank lines at the start of the file when true
+	TrimLeadingBlankLines *bool `json:"trim_leading_blank_lines,omitempty"`
+	// MaxBlankLines is the largest number of consecutive blank lines kept; nil keeps them all
+	MaxBlankLines *int `json:"max_blank_lines,omitempty"`
+	// ControlCharacters selects what happens to ANSI escape sequences and control characters
+	// other than tab, LF, CR and FF (strip or report); empty leaves them alone
+	ControlCharacters string `json:"control_characters,omitempty"`
+	// Charset declares the legacy charset the file is encoded in (shift_jis, euc-jp or
+	// latin-1); empty means UTF-8 or an encoding detected from the content
+	Charset string `json:"charset,omitempty"`
+	// TranscodeUTF8 converts files of a declared charset that were written as UTF-8
+	// back to that charset when true, instead of leaving them alone
+	TranscodeUTF8 *bool `json:"transcode_utf8,omitempty"`
+	// ProcessBinary fixes files that look binary instead of skipping them when true
+	ProcessBinary *bool `json:"process_binary,omitempty"`
+}
+
+// validate checks that every field holds an accepted value
+func (p *Policy) validate() error {
+	if p.FinalNewline != "" && !slices.Contains(finalNewlinePolicies, p.FinalNewline) {
+		return fmt.Errorf("final_newline must be one of %q, got %q", finalNewlinePolicies, p.FinalNewline)
+	}
+	if p.BOM != "" && !slices.Contains(bomPolicies, p.BOM) {
+		return fmt.Errorf("bom must be one of %q, got %q", bomPolicies, p.BOM)
+	}
+	if p.EndOfLine != "" && !slices.Contains(endOfLinePolicies, p.EndOfLine) {
+		return fmt.Errorf("end_of_line must be one of %q, got %q", endOfLinePolicies, p.EndOfLine)
+	}
+	if p.MaxBlankLines != nil && *p.MaxBlankLines < 0 {
+		return fmt.Errorf("max_blank_lines must not be negative, got %d", *p.MaxBlankLines)
+	}
+	if p.ControlCharacters != "" && !slices.Contains(controlCharacterPolicies, p.ControlCharacters) {
+		return fmt.Errorf("control_characters must be one of %q, got %q", controlCharacterPolicies, p.ControlCharacters)
+	}
+	if p.Charset != "" && !slices.Contains(charsets, p.Charset) {
+		return fmt.Errorf("charset must be one of %q, got %q", charsets, p.Charset)
+	}
+	if p.IndentStyle != "" && !slices.Contains(indentStyles, p.IndentStyle) {
+		return fmt.Errorf("indent_style must be one of %q, got %q", indentStyles, p.IndentStyle)
+	}
+	if p.IndentSize < 0 || p.IndentSize > maxIndentSize {
+		return fmt.Errorf("indent_size must be between 1 and %d, or 0 for the default, got %d", maxIndentSize, p.IndentSize)
+	}
+	return nil
+}
+
+// Rule refines the policy for files whose name matches a glob pattern
+type Rule struct {
+	// Pattern is a glob pattern matched against the file name, like --include
+	Pattern string `json:"pattern"`
+	Policy
+}
+
+// PolicySet holds the default policy and the per-pattern rules that refine it
+type PolicySet struct {
+	Policy
+	// Rules refine the default policy in order, so later rules win
+	Rules []Rule `json:"rules,omitempty"`
+}
+
+// validate checks the default policy and every rule
+func (ps *PolicySet) validate() error {
+	if err := ps.Policy.validate(); err != nil {
+		return err
+	}
+	for i, rule := range ps.Rules {
+		if rule.Pattern == "" {
+			return fmt.Errorf("rule %d: pattern is required", i+1)
+		}
+		if _, err := filepath.Match(rule.Pattern, ""); err != nil {
+			return fmt.Errorf("rule %d: invalid pattern %q: %w", i+1, rule.Pattern, err)
+		}
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// loadPolicySet reads a JSON policy file, rejecting unknown keys and values
+func loadPolicySet(path string) (PolicySet, error) {
+	var set PolicySet
+	file, err := os.Open(path)
+	if err != nil {
+		return set, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&set); err != nil {
+		return PolicySet{}, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	if err := set.validate(); err != nil {
+		return PolicySet{}, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return set, nil
+}
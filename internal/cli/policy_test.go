@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadPolicySet(t *testing.T) {
+	trimOn, trimOff := true, false
+	zero, two := 0, 2
+
+	tests := []struct {
+		name      string
+		content   string
+		expected  PolicySet
+		expectErr bool
+	}{
+		{
+			name:     "empty object",
+			content:  `{}`,
+			expected: PolicySet{},
+		},
+		{
+			name:     "default policy",
+			content:  `{"final_newline": "exactly-one"}`,
+			expected: PolicySet{Policy: Policy{FinalNewline: FinalNewlineExactlyOne}},
+		},
+		{
+			name:    "rules",
+			content: `{"final_newline": "exactly-one", "rules": [{"pattern": "*.md", "final_newline": "ensure"}]}`,
+			expected: PolicySet{
+				Policy: Policy{FinalNewline: FinalNewlineExactlyOne},
+				Rules:  []Rule{{Pattern: "*.md", Policy: Policy{FinalNewline: FinalNewlineEnsure}}},
+			},
+		},
+		{
+			name:     "trailing whitespace",
+			content:  `{"trim_trailing_whitespace": true, "rules": [{"pattern": "*.md", "trim_trailing_whitespace": false}]}`,
+			expected: PolicySet{Policy: Policy{TrimTrailingWhitespace: &trimOn}, Rules: []Rule{{Pattern: "*.md", Policy: Policy{TrimTrailingWhitespace: &trimOff}}}},
+		},
+		{
+			name:     "no final newline",
+			content:  `{"rules": [{"pattern": "*.golden", "final_newline": "none"}]}`,
+			expected: PolicySet{Rules: []Rule{{Pattern: "*.golden", Policy: Policy{FinalNewline: FinalNewlineNone}}}},
+		},
+		{
+			name:     "byte order mark",
+			content:  `{"bom": "strip", "rules": [{"pattern": "*.ps1", "bom": "require"}]}`,
+			expected: PolicySet{Policy: Policy{BOM: BOMStrip}, Rules: []Rule{{Pattern: "*.ps1", Policy: Policy{BOM: BOMRequire}}}},
+		},
+		{
+			name:     "end of line",
+			content:  `{"end_of_line": "lf", "rules": [{"pattern": "*.bat", "end_of_line": "crlf"}]}`,
+			expected: PolicySet{Policy: Policy{EndOfLine: EndOfLineLF}, Rules: []Rule{{Pattern: "*.bat", Policy: Policy{EndOfLine: EndOfLineCRLF}}}},
+		},
+		{
+			name:     "indentation",
+			content:  `{"indent_style": "space", "rules": [{"pattern": "*.go", "indent_style": "tab"}, {"pattern": "*.js", "indent_size": 2}]}`,
+			expected: PolicySet{Policy: Policy{IndentStyle: IndentStyleSpace}, Rules: []Rule{{Pattern: "*.go", Policy: Policy{IndentStyle: IndentStyleTab}}, {Pattern: "*.js", Policy: Policy{IndentSize: 2}}}},
+		},
+		{
+			name:     "unicode cleanup",
+			content:  `{"unicode_cleanup": true, "rules": [{"pattern": "*.md", "allow_typographic": true}]}`,
+			expected: PolicySet{Policy: Policy{UnicodeCleanup: &trimOn}, Rules: []Rule{{Pattern: "*.md", Policy: Policy{AllowTypographic: &trimOn}}}},
+		},
+		{
+			name:     "control characters",
+			content:  `{"control_characters": "strip", "rules": [{"pattern": "*.log", "control_characters": "report"}]}`,
+			expected: PolicySet{Policy: Policy{ControlCharacters: ControlCharactersStrip}, Rules: []Rule{{Pattern: "*.log", Policy: Policy{ControlCharacters: ControlCharactersReport}}}},
+		},
+		{name: "unknown control characters value", content: `{"control_characters": "remove"}`, expectErr: true},
+		{
+			name:     "blank lines",
+			content:  `{"trim_leading_blank_lines": true, "max_blank_lines": 2, "rules": [{"pattern": "*.py", "max_blank_lines": 0}]}`,
+			expected: PolicySet{Policy: Policy{TrimLeadingBlankLines: &trimOn, MaxBlankLines: &two}, Rules: []Rule{{Pattern: "*.py", Policy: Policy{MaxBlankLines: &zero}}}},
+		},
+		{name: "negative max blank lines", content: `{"max_blank_lines": -1}`, expectErr: true},
+		{
+			name:     "charset",
+			content:  `{"rules": [{"pattern": "*.sjis.txt", "charset": "shift_jis", "transcode_utf8": true}]}`,
+			expected: PolicySet{Rules: []Rule{{Pattern: "*.sjis.txt", Policy: Policy{Charset: CharsetShiftJIS, TranscodeUTF8: &trimOn}}}},
+		},
+		{name: "unknown charset", content: `{"charset": "cp932"}`, expectErr: true},
+		{name: "unknown indent style", content: `{"indent_style": "tabs"}`, expectErr: true},
+		{name: "zero indent size uses the default", content: `{"indent_size": 0}`, expected: PolicySet{}},
+		{name: "negative indent size", content: `{"indent_size": -1}`, expectErr: true},
+		{name: "indent size too large", content: `{"indent_size": 17}`, expectErr: true},
+		{name: "unknown end of line value", content: `{"end_of_line": "cr"}`, expectErr: true},
+		{name: "unknown bom value", content: `{"bom": "utf-8"}`, expectErr: true},
+		{name: "unknown policy value", content: `{"final_newline": "always"}`, expectErr: true},
+		{name: "unknown rule value", content: `{"rules": [{"pattern": "*.md", "final_newline": "always"}]}`, expectErr: true},
+		{name: "unknown key", content: `{"final_newlines": "ensure"}`, expectErr: true},
+		{name: "missing pattern", content: `{"rules": [{"final_newline": "ensure"}]}`, expectErr: true},
+		{name: "invalid pattern", content: `{"rules": [{"pattern": "[", "final_newline": "ensure"}]}`, expectErr: true},
+		{name: "malformed JSON", content: `{"final_newline": `, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ccnewline.json")
+			_ = os.WriteFile(path, []byte(tt.content), 0o644)
+
+			result, err := loadPolicySet(path)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("loadPolicySet() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !tt.expectErr && !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("loadPolicySet() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadPolicySetMissingFile(t *testing.T) {
+	if _, err := loadPolicySet(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing policy file")
+	}
+}
+
+func TestParseFlagsConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ccnewline.json")
+	_ = os.WriteFile(path, []byte(`{"final_newline": "exactly-one"}`), 0o644)
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	for _, args := range [][]string{{"--config", path}, {"-c", path}} {
+		os.Args = append([]string{"test"}, args...)
+
+		result := newFlagParser().parse()
+		if result.Policies.FinalNewline != FinalNewlineExactlyOne {
+			t.Errorf("%v: Policies.FinalNewline = %q, want %q", args, result.Policies.FinalNewline, FinalNewlineExactlyOne)
+		}
+	}
+}
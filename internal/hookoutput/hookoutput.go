@@ -0,0 +1,47 @@
+// Package hookoutput provides the JSON response format understood by Claude Code hooks.
+// Writing a response lets ccnewline report back to Claude and the user what it changed.
+package hookoutput
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Permission decisions for PreToolUse responses
+const (
+	// PermissionAllow lets the tool call run without prompting the user
+	PermissionAllow = "allow"
+	// PermissionAsk prompts the user to confirm the tool call
+	PermissionAsk = "ask"
+)
+
+// Response is the JSON object a hook command may print on stdout
+type Response struct {
+	// SuppressOutput hides the hook's stdout from the transcript
+	SuppressOutput bool `json:"suppressOutput,omitempty"`
+	// SystemMessage is a message shown to the user
+	SystemMessage string `json:"systemMessage,omitempty"`
+	// HookSpecificOutput carries event-specific fields
+	HookSpecificOutput *HookSpecificOutput `json:"hookSpecificOutput,omitempty"`
+}
+
+// HookSpecificOutput holds fields that depend on the hook event
+type HookSpecificOutput struct {
+	// HookEventName is the event the output applies to, such as PostToolUse
+	HookEventName string `json:"hookEventName"`
+	// AdditionalContext is added to the context Claude sees
+	AdditionalContext string `json:"additionalContext,omitempty"`
+	// PermissionDecision is "allow", "deny" or "ask" for PreToolUse
+	PermissionDecision string `json:"permissionDecision,omitempty"`
+	// PermissionDecisionReason explains the permission decision
+	PermissionDecisionReason string `json:"permissionDecisionReason,omitempty"`
+	// UpdatedInput replaces the tool input for PreToolUse
+	UpdatedInput json.RawMessage `json:"updatedInput,omitempty"`
+}
+
+// Write encodes the response as a single line of JSON
+func Write(w io.Writer, resp *Response) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(resp)
+}
@@ -0,0 +1,59 @@
+package hookoutput
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     *Response
+		expected string
+	}{
+		{
+			name:     "empty response",
+			resp:     &Response{},
+			expected: "{}\n",
+		},
+		{
+			name: "post tool use context",
+			resp: &Response{
+				SuppressOutput: true,
+				SystemMessage:  "ccnewline: added final newline to main.go",
+				HookSpecificOutput: &HookSpecificOutput{
+					HookEventName:     "PostToolUse",
+					AdditionalContext: "main.go <changed>",
+				},
+			},
+			expected: `{"suppressOutput":true,"systemMessage":"ccnewline: added final newline to main.go",` +
+				`"hookSpecificOutput":{"hookEventName":"PostToolUse","additionalContext":"main.go <changed>"}}` + "\n",
+		},
+		{
+			name: "pre tool use updated input",
+			resp: &Response{
+				SuppressOutput: true,
+				HookSpecificOutput: &HookSpecificOutput{
+					HookEventName:            "PreToolUse",
+					PermissionDecision:       PermissionAsk,
+					PermissionDecisionReason: "ccnewline added final newline in main.go",
+					UpdatedInput:             []byte(`{"content":"package main\n"}`),
+				},
+			},
+			expected: `{"suppressOutput":true,"hookSpecificOutput":{"hookEventName":"PreToolUse","permissionDecision":"ask",` +
+				`"permissionDecisionReason":"ccnewline added final newline in main.go","updatedInput":{"content":"package main\n"}}}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.resp); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Write() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
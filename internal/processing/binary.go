@@ -0,0 +1,105 @@
+package processing
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxInvalidUTF8Ratio is the share of bytes that are not valid UTF-8 above which
+// content without a wide encoding is considered binary
+const maxInvalidUTF8Ratio = 0.3
+
+// maxUnprintableRatio is the share of characters that are neither printable nor
+// tabs and line breaks above which UTF-16 or UTF-32 content is considered binary
+const maxUnprintableRatio = 0.01
+
+// binarySignature identifies a binary file format by the bytes it starts with
+type binarySignature struct {
+	name  string
+	magic []byte
+}
+
+// binarySignatures lists the formats recognized by their magic number
+var binarySignatures = []binarySignature{
+	{name: "PNG image", magic: []byte("\x89PNG\r\n\x1a\n")},
+	{name: "JPEG image", magic: []byte{0xff, 0xd8, 0xff}},
+	{name: "GIF image", magic: []byte("GIF87a")},
+	{name: "GIF image", magic: []byte("GIF89a")},
+	{name: "PDF document", magic: []byte("%PDF-")},
+	{name: "ZIP archive", magic: []byte("PK\x03\x04")},
+	{name: "gzip archive", magic: []byte{0x1f, 0x8b}},
+	{name: "xz archive", magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{name: "zstd archive", magic: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{name: "7z archive", magic: []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}},
+	{name: "SQLite database", magic: []byte("SQLite format 3\x00")},
+	{name: "ELF executable", magic: []byte("\x7fELF")},
+	{name: "Mach-O executable", magic: []byte{0xcf, 0xfa, 0xed, 0xfe}},
+	{name: "Mach-O executable", magic: []byte{0xce, 0xfa, 0xed, 0xfe}},
+	{name: "Java class file", magic: []byte{0xca, 0xfe, 0xba, 0xbe}},
+	{name: "WebAssembly module", magic: []byte("\x00asm")},
+}
+
+// detectBinary examines the first block of a file and returns why it looks binary,
+// or "" when it looks like text. Magic numbers are checked first. UTF-16 and UTF-32
+// content is decoded and checked for NUL and other unprintable characters; other
+// content is checked for NUL bytes and invalid UTF-8.
+func detectBinary(head []byte, encoding *textEncoding) string {
+	for _, signature := range binarySignatures {
+		if bytes.HasPrefix(head, signature.magic) {
+			return signature.name
+		}
+	}
+	if encoding.isWide() {
+		return describeUnprintable(encoding.decodeHead(head))
+	}
+
+	if bytes.IndexByte(head, 0) >= 0 {
+		return "NUL bytes"
+	}
+	// Legacy charsets are not UTF-8, and are verified when the file is decoded
+	if encoding.codec != nil {
+		return ""
+	}
+	if invalid := countInvalidUTF8(head); invalid > 0 && float64(invalid) > float64(len(head))*maxInvalidUTF8Ratio {
+		return fmt.Sprintf("%d of %d bytes are not valid UTF-8", invalid, len(head))
+	}
+	return ""
+}
+
+// describeUnprintable returns why decoded text looks binary, or "" when it is
+// mostly printable characters, tabs and line breaks
+func describeUnprintable(text []rune) string {
+	unprintable := 0
+	for _, r := range text {
+		switch {
+		case r == 0:
+			return "NUL characters"
+		case r == '\t' || r == '\n' || r == '\r' || r == '\f' || unicode.IsGraphic(r):
+		default:
+			unprintable++
+		}
+	}
+	if unprintable > 0 && float64(unprintable) > float64(len(text))*maxUnprintableRatio {
+		return fmt.Sprintf("%d of %d characters are not printable", unprintable, len(text))
+	}
+	return ""
+}
+
+// countInvalidUTF8 counts the bytes of content that are not part of valid UTF-8.
+// A character cut off at the end of the content is not counted.
+func countInvalidUTF8(content []byte) int {
+	invalid := 0
+	for len(content) > 0 {
+		r, size := utf8.DecodeRune(content)
+		if r == utf8.RuneError && size == 1 {
+			if !utf8.FullRune(content) {
+				break
+			}
+			invalid++
+		}
+		content = content[size:]
+	}
+	return invalid
+}
@@ -0,0 +1,153 @@
+package processing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+// integerArray encodes the integers from first to last as little-endian values of size bytes
+func integerArray(size int, first, last int) []byte {
+	var data []byte
+	for i := first; i <= last; i++ {
+		switch size {
+		case 2:
+			data = binary.LittleEndian.AppendUint16(data, uint16(i))
+		case 4:
+			data = binary.LittleEndian.AppendUint32(data, uint32(i))
+		}
+	}
+	return data
+}
+
+func TestDetectBinary(t *testing.T) {
+	tests := []struct {
+		name     string
+		head     []byte
+		encoding *textEncoding
+		expected string
+	}{
+		{name: "text", head: []byte("package main\n"), encoding: encodingUTF8, expected: ""},
+		{name: "UTF-8 multibyte", head: []byte("日本語のテキスト\n"), encoding: encodingUTF8, expected: ""},
+		{name: "empty", head: nil, encoding: encodingUTF8, expected: ""},
+		{name: "PNG", head: []byte("\x89PNG\r\n\x1a\nrest"), encoding: encodingUTF8, expected: "PNG image"},
+		{name: "SQLite", head: []byte("SQLite format 3\x00..."), encoding: encodingUTF8, expected: "SQLite database"},
+		{name: "ELF", head: []byte("\x7fELF\x02\x01\x01"), encoding: encodingUTF8, expected: "ELF executable"},
+		{name: "NUL bytes", head: []byte("abc\x00def"), encoding: encodingUTF8, expected: "NUL bytes"},
+		{name: "UTF-16 is not binary", head: mustEncode(encodingUTF16LE, "hello\n"), encoding: encodingUTF16LE, expected: ""},
+		{name: "UTF-16 with byte order mark", head: append([]byte{0xff, 0xfe}, mustEncode(encodingUTF16LE, "日本語\u3000です\r\n")...), encoding: encodingUTF16LE, expected: ""},
+		{name: "UTF-16 with NUL characters", head: mustEncode(encodingUTF16LE, "a\x00b\n"), encoding: encodingUTF16LE, expected: "NUL characters"},
+		{name: "UTF-16 control characters", head: integerArray(2, 1, 200), encoding: encodingUTF16LE, expected: "61 of 200 characters are not printable"},
+		{name: "UTF-32 control characters", head: integerArray(4, 1, 100), encoding: encodingUTF32LE, expected: "27 of 100 characters are not printable"},
+		{name: "Shift_JIS is not binary", head: mustEncode(charsetEncodings[cli.CharsetShiftJIS], "日本語\n"), encoding: charsetEncodings[cli.CharsetShiftJIS], expected: ""},
+		{name: "mostly invalid UTF-8", head: []byte{0x80, 0x81, 0xfe, 'a', 0xff, 0x90}, encoding: encodingUTF8, expected: "5 of 6 bytes are not valid UTF-8"},
+		{name: "some invalid UTF-8", head: []byte("caf\xe9 au lait\n"), encoding: encodingUTF8, expected: ""},
+		{name: "character cut off at the end", head: []byte("ab\xe3\x81"), encoding: encodingUTF8, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := detectBinary(tt.head, tt.encoding); result != tt.expected {
+				t.Errorf("detectBinary() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCountInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		expected int
+	}{
+		{name: "valid", content: []byte("héllo"), expected: 0},
+		{name: "Latin-1", content: []byte("h\xe9llo"), expected: 1},
+		{name: "truncated at end", content: []byte("a\xe3\x81"), expected: 0},
+		{name: "truncated in the middle", content: []byte("a\xe3\x81b"), expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := countInvalidUTF8(tt.content); result != tt.expected {
+				t.Errorf("countInvalidUTF8(%q) = %d, want %d", tt.content, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectEncodingIntegerArrays(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{name: "uint32 values", content: integerArray(4, 1, 1999)},
+		{name: "uint16 values", content: integerArray(2, 1, 1999)},
+		{name: "small uint16 values", content: integerArray(2, 1, 200)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head := tt.content[:min(len(tt.content), sniffSize)]
+			encoding, _ := detectEncoding(head)
+			if encoding != encodingUTF8 {
+				t.Errorf("detectEncoding() = %s, want UTF-8", encoding.name)
+			}
+			if reason := detectBinary(head, encoding); reason != "NUL bytes" {
+				t.Errorf("detectBinary() = %q, want NUL bytes", reason)
+			}
+		})
+	}
+}
+
+func TestAddNewlineIfNeededBinary(t *testing.T) {
+	on := true
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name          string
+		content       []byte
+		options       processOptions
+		expected      []byte
+		expectSkipped string
+	}{
+		{name: "PNG skipped", content: png, expected: png, expectSkipped: skipBinary},
+		{name: "NUL bytes skipped", content: []byte("data\x00more"), expected: []byte("data\x00more"), expectSkipped: skipBinary},
+		{
+			name:     "binary processed when allowed",
+			content:  []byte("data\x00more"),
+			options:  processOptions{policies: newPolicyResolver(cli.PolicySet{Policy: cli.Policy{ProcessBinary: &on}})},
+			expected: []byte("data\x00more\n"),
+		},
+		{name: "uint32 array skipped", content: integerArray(4, 1, 1999), expected: integerArray(4, 1, 1999), expectSkipped: skipBinary},
+		{name: "uint16 array skipped", content: integerArray(2, 1, 200), expected: integerArray(2, 1, 200), expectSkipped: skipBinary},
+		{name: "text processed", content: []byte("text"), expected: []byte("text\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file")
+			_ = os.WriteFile(filePath, tt.content, 0o644)
+
+			logger := &mockLogger{}
+			result := FileResult{Path: filePath}
+			if err := addNewlineIfNeeded(logger, filePath, tt.options, &result); err != nil {
+				t.Fatalf("addNewlineIfNeeded() error = %v", err)
+			}
+			content, _ := os.ReadFile(filePath)
+			if !bytes.Equal(content, tt.expected) {
+				t.Errorf("File content = %q, want %q", content, tt.expected)
+			}
+			if result.Skipped != tt.expectSkipped {
+				t.Errorf("Skipped = %q, want %q", result.Skipped, tt.expectSkipped)
+			}
+			if tt.expectSkipped != "" && !strings.Contains(strings.Join(logger.debugMessages, "\n"), "Skipped: binary") {
+				t.Errorf("Debug output should report the binary skip, got %v", logger.debugMessages)
+			}
+		})
+	}
+}
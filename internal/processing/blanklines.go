@@ -0,0 +1,255 @@
+package processing
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/logging"
+)
+
+// Descriptions of changes to the blank lines of a file
+const (
+	// changeRemovedLeadingBlankLines records that blank lines at the start were removed
+	changeRemovedLeadingBlankLines = "removed %d leading blank line(s)"
+	// changeCollapsedBlankLines records that runs of blank lines were shortened
+	changeCollapsedBlankLines = "removed %d blank line(s) beyond runs of %d"
+
+	// changeRemovedBlankLines records that trailing blank lines were removed
+	changeRemovedBlankLines = "removed %d trailing blank line(s)"
+	// changeRemovedNewline records that the final line endings were removed
+	changeRemovedNewline = "removed final newline"
+)
+
+// tailChunkSize is how much of the end of a file is read at a time when looking
+// for trailing line breaks
+const tailChunkSize = 4096
+
+// isLineBreakByte reports whether b is part of a line ending
+func isLineBreakByte(b byte) bool {
+	return b == newlineByte || b == carriageReturnByte
+}
+
+// countLineBreaks counts the line endings in a run of CR and LF bytes and returns
+// the length of the first one, treating CRLF as a single line ending
+func countLineBreaks(run []byte) (count, firstLength int) {
+	for i := 0; i < len(run); i++ {
+		length := 1
+		if run[i] == carriageReturnByte && i+1 < len(run) && run[i+1] == newlineByte {
+			length = 2
+			i++
+		}
+		if count == 0 {
+			firstLength = length
+		}
+		count++
+	}
+	return count, firstLength
+}
+
+// readTrailingBreaks returns the offset at which the line breaks ending a file start,
+// together with those line breaks. Only the tail of the file is read.
+func readTrailingBreaks(file *os.File) (int64, []byte, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var run []byte
+	end := info.Size()
+	for end > 0 {
+		start := max(end-tailChunkSize, 0)
+		chunk := make([]byte, end-start)
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return 0, nil, err
+		}
+
+		i := len(chunk)
+		for i > 0 && isLineBreakByte(chunk[i-1]) {
+			i--
+		}
+		run = append(chunk[i:], run...)
+		if i > 0 {
+			return start + int64(i), run, nil
+		}
+		end = start
+	}
+	return 0, run, nil
+}
+
+// collapseTrailingBlankLines truncates the blank lines at the end of a file so that it
+// ends with a single line break. Only the tail is read and the file is never rewritten.
+// Files made up of nothing but line breaks are left alone.
+func collapseTrailingBlankLines(logger logging.Logger, filePath string, options processOptions, result *FileResult) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to check trailing blank lines: %w", err)
+	}
+	contentEnd, run, err := readTrailingBreaks(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to check trailing blank lines: %w", err)
+	}
+
+	if contentEnd == 0 {
+		logger.Debug("│ Contains only line breaks, not collapsing")
+		return nil
+	}
+	breaks, firstLength := countLineBreaks(run)
+	if breaks <= 1 {
+		return nil
+	}
+
+	change := fmt.Sprintf(changeRemovedBlankLines, breaks-1)
+	if options.dryRun {
+		logger.Debug(fmt.Sprintf("│ %d trailing blank line(s) (dry run, not modified)", breaks-1))
+		result.Changes = append(result.Changes, change)
+		return nil
+	}
+
+	logger.Debug(fmt.Sprintf("│ Removing %d trailing blank line(s)", breaks-1))
+	if err := os.Truncate(filePath, contentEnd+int64(firstLength)); err != nil {
+		return fmt.Errorf("failed to remove trailing blank lines: %w", err)
+	}
+
+	result.Changes = append(result.Changes, change)
+	logger.Info(fmt.Sprintf("Removed %d trailing blank line(s) from %s", breaks-1, filePath))
+	return nil
+}
+
+// removeFinalNewline truncates the line breaks at the end of a file, for files that must
+// not end with a newline. Only the tail is read, and files made up of nothing but
+// line breaks are left alone.
+func removeFinalNewline(logger logging.Logger, filePath string, options processOptions, result *FileResult) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to check final newline: %w", err)
+	}
+	contentEnd, run, err := readTrailingBreaks(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to check final newline: %w", err)
+	}
+
+	switch {
+	case len(run) == 0:
+		logger.Debug("│ Already ends without newline")
+		return nil
+	case contentEnd == 0:
+		logger.Debug("│ Contains only line breaks, not removing")
+		return nil
+	}
+
+	if options.dryRun {
+		logger.Debug("│ Final newline present (dry run, not modified)")
+		result.Changes = append(result.Changes, changeRemovedNewline)
+		return nil
+	}
+
+	logger.Debug(fmt.Sprintf("│ Removing final line breaks %q", run))
+	if err := os.Truncate(filePath, contentEnd); err != nil {
+		return fmt.Errorf("failed to remove final newline: %w", err)
+	}
+
+	result.Changes = append(result.Changes, changeRemovedNewline)
+	logger.Info(fmt.Sprintf("Removed final newline from %s", filePath))
+	return nil
+}
+
+// collapseTrailingBlankLinesInContent returns content with the blank lines at its end
+// removed, keeping a single line break, and the number of lines removed
+func collapseTrailingBlankLinesInContent(content string) (string, int) {
+	contentEnd := len(strings.TrimRight(content, "\r\n"))
+	if contentEnd == 0 {
+		return content, 0
+	}
+	breaks, firstLength := countLineBreaks([]byte(content[contentEnd:]))
+	if breaks <= 1 {
+		return content, 0
+	}
+	return content[:contentEnd+firstLength], breaks - 1
+}
+
+// blankLineFixer removes the blank lines at the start of the content and shortens
+// runs of consecutive blank lines. As for trailing blank lines, only empty lines are
+// blank; lines holding spaces or tabs are left to the trailing whitespace fixer, which
+// runs first.
+type blankLineFixer struct {
+	// trimLeading removes the blank lines before the first line of text
+	trimLeading bool
+	// maxRun is the largest number of consecutive blank lines kept, or -1 to keep them all
+	maxRun int
+	// tracker follows the file's string literals, whose blank lines are kept;
+	// nil when they are not tracked
+	tracker literalTracker
+}
+
+// newBlankLineFixer creates a blank line fixer for the file and policy, or returns nil
+// when the policy leaves blank lines alone
+func newBlankLineFixer(filePath string, policy cli.Policy) *blankLineFixer {
+	trimLeading := enabled(policy.TrimLeadingBlankLines)
+	if !trimLeading && policy.MaxBlankLines == nil {
+		return nil
+	}
+	maxRun := -1
+	if policy.MaxBlankLines != nil {
+		maxRun = *policy.MaxBlankLines
+	}
+	return &blankLineFixer{trimLeading: trimLeading, maxRun: maxRun, tracker: newLiteralTracker(filePath)}
+}
+
+// fix removes the extra blank lines outside string literals, keeping a leading byte order mark.
+// Content made only of blank lines is left alone, like trailing blank lines are.
+func (blf *blankLineFixer) fix(content []byte) ([]byte, string) {
+	if len(bytes.Trim(content, "\r\n")) == 0 {
+		return nil, ""
+	}
+
+	var fixed bytes.Buffer
+	if bytes.HasPrefix(content, utf8BOM) {
+		fixed.Write(utf8BOM)
+		content = content[len(utf8BOM):]
+	}
+
+	leading, run := true, 0
+	removedLeading, removedRuns := 0, 0
+	for len(content) > 0 {
+		end := bytes.IndexByte(content, newlineByte)
+		line, rest := content, []byte(nil)
+		if end >= 0 {
+			line, rest = content[:end+1], content[end+1:]
+		}
+		content = rest
+
+		inLiteral := blf.tracker != nil && blf.tracker.next(string(line))
+		// The last line is blank only when it ends with a line break
+		if inLiteral || end < 0 || len(bytes.TrimRight(line, "\r\n")) > 0 {
+			leading, run = false, 0
+			fixed.Write(line)
+			continue
+		}
+		switch {
+		case leading && blf.trimLeading:
+			removedLeading++
+		case blf.maxRun >= 0 && run >= blf.maxRun:
+			removedRuns++
+		default:
+			run++
+			fixed.Write(line)
+		}
+	}
+
+	var changes []string
+	if removedLeading > 0 {
+		changes = append(changes, fmt.Sprintf(changeRemovedLeadingBlankLines, removedLeading))
+	}
+	if removedRuns > 0 {
+		changes = append(changes, fmt.Sprintf(changeCollapsedBlankLines, removedRuns, blf.maxRun))
+	}
+	if len(changes) == 0 {
+		return nil, ""
+	}
+	return fixed.Bytes(), strings.Join(changes, ", ")
+}
@@ -0,0 +1,399 @@
+package processing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+func TestCountLineBreaks(t *testing.T) {
+	tests := []struct {
+		name              string
+		run               string
+		expectCount       int
+		expectFirstLength int
+	}{
+		{name: "empty", run: "", expectCount: 0, expectFirstLength: 0},
+		{name: "single LF", run: "\n", expectCount: 1, expectFirstLength: 1},
+		{name: "single CRLF", run: "\r\n", expectCount: 1, expectFirstLength: 2},
+		{name: "LF run", run: "\n\n\n", expectCount: 3, expectFirstLength: 1},
+		{name: "CRLF run", run: "\r\n\r\n", expectCount: 2, expectFirstLength: 2},
+		{name: "bare CR then CRLF", run: "\r\r\n", expectCount: 2, expectFirstLength: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, firstLength := countLineBreaks([]byte(tt.run))
+			if count != tt.expectCount || firstLength != tt.expectFirstLength {
+				t.Errorf("countLineBreaks(%q) = %d, %d, want %d, %d", tt.run, count, firstLength, tt.expectCount, tt.expectFirstLength)
+			}
+		})
+	}
+}
+
+func TestReadTrailingBreaks(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		expectOffset int64
+		expectRun    string
+	}{
+		{name: "no trailing breaks", content: "abc", expectOffset: 3, expectRun: ""},
+		{name: "trailing breaks", content: "abc\n\r\n", expectOffset: 3, expectRun: "\n\r\n"},
+		{name: "only breaks", content: "\n\n", expectOffset: 0, expectRun: "\n\n"},
+		{
+			name:         "breaks longer than a chunk",
+			content:      "abc" + strings.Repeat("\n", tailChunkSize+10),
+			expectOffset: 3,
+			expectRun:    strings.Repeat("\n", tailChunkSize+10),
+		},
+		{
+			name:         "content longer than a chunk",
+			content:      strings.Repeat("x", tailChunkSize*2) + "\n\n",
+			expectOffset: tailChunkSize * 2,
+			expectRun:    "\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file.txt")
+			_ = os.WriteFile(filePath, []byte(tt.content), 0o644)
+			file, _ := os.Open(filePath)
+			defer file.Close()
+
+			offset, run, err := readTrailingBreaks(file)
+			if err != nil {
+				t.Fatalf("readTrailingBreaks() error = %v", err)
+			}
+			if offset != tt.expectOffset || string(run) != tt.expectRun {
+				t.Errorf("readTrailingBreaks() = %d, %q, want %d, %q", offset, run, tt.expectOffset, tt.expectRun)
+			}
+		})
+	}
+}
+
+func TestCollapseTrailingBlankLinesInContent(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expected      string
+		expectRemoved int
+	}{
+		{name: "single newline", content: "a\n", expected: "a\n", expectRemoved: 0},
+		{name: "no newline", content: "a", expected: "a", expectRemoved: 0},
+		{name: "blank lines", content: "a\n\n\n", expected: "a\n", expectRemoved: 2},
+		{name: "CRLF blank lines", content: "a\r\n\r\n\r\n", expected: "a\r\n", expectRemoved: 2},
+		{name: "whitespace line is kept", content: "a\n  \n\n", expected: "a\n  \n", expectRemoved: 1},
+		{name: "only breaks", content: "\n\n", expected: "\n\n", expectRemoved: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, removed := collapseTrailingBlankLinesInContent(tt.content)
+			if result != tt.expected || removed != tt.expectRemoved {
+				t.Errorf("collapseTrailingBlankLinesInContent(%q) = %q, %d, want %q, %d", tt.content, result, removed, tt.expected, tt.expectRemoved)
+			}
+		})
+	}
+}
+
+func TestAddNewlineIfNeededExactlyOne(t *testing.T) {
+	exactlyOne := newPolicyResolver(cli.PolicySet{Policy: cli.Policy{FinalNewline: cli.FinalNewlineExactlyOne}})
+
+	tests := []struct {
+		name          string
+		content       string
+		options       processOptions
+		expected      string
+		expectChanges []string
+	}{
+		{
+			name:          "blank lines collapsed",
+			content:       "a\n\n\n",
+			options:       processOptions{policies: exactlyOne},
+			expected:      "a\n",
+			expectChanges: []string{"removed 2 trailing blank line(s)"},
+		},
+		{
+			name:          "CRLF blank lines collapsed",
+			content:       "a\r\nb\r\n\r\n",
+			options:       processOptions{policies: exactlyOne},
+			expected:      "a\r\nb\r\n",
+			expectChanges: []string{"removed 1 trailing blank line(s)"},
+		},
+		{
+			name:          "missing newline added",
+			content:       "a",
+			options:       processOptions{policies: exactlyOne},
+			expected:      "a\n",
+			expectChanges: []string{changeAddedNewline},
+		},
+		{
+			name:     "single newline untouched",
+			content:  "a\n",
+			options:  processOptions{policies: exactlyOne},
+			expected: "a\n",
+		},
+		{
+			name:     "only line breaks untouched",
+			content:  "\n\n",
+			options:  processOptions{policies: exactlyOne},
+			expected: "\n\n",
+		},
+		{
+			name:          "dry run",
+			content:       "a\n\n",
+			options:       processOptions{policies: exactlyOne, dryRun: true},
+			expected:      "a\n\n",
+			expectChanges: []string{"removed 1 trailing blank line(s)"},
+		},
+		{
+			name:     "ensure policy keeps blank lines",
+			content:  "a\n\n",
+			options:  processOptions{},
+			expected: "a\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file.txt")
+			_ = os.WriteFile(filePath, []byte(tt.content), 0o644)
+
+			result := FileResult{Path: filePath}
+			if err := addNewlineIfNeeded(&mockLogger{}, filePath, tt.options, &result); err != nil {
+				t.Fatalf("addNewlineIfNeeded() error = %v", err)
+			}
+			content, _ := os.ReadFile(filePath)
+			if string(content) != tt.expected {
+				t.Errorf("File content = %q, want %q", content, tt.expected)
+			}
+			if strings.Join(result.Changes, "|") != strings.Join(tt.expectChanges, "|") {
+				t.Errorf("Changes = %v, want %v", result.Changes, tt.expectChanges)
+			}
+		})
+	}
+}
+
+func TestAddNewlineIfNeededNone(t *testing.T) {
+	none := newPolicyResolver(cli.PolicySet{Rules: []cli.Rule{{Pattern: "*.golden", Policy: cli.Policy{FinalNewline: cli.FinalNewlineNone}}}})
+
+	tests := []struct {
+		name          string
+		fileName      string
+		content       string
+		dryRun        bool
+		expected      string
+		expectChanges []string
+	}{
+		{name: "newline removed", fileName: "out.golden", content: "a\n", expected: "a", expectChanges: []string{changeRemovedNewline}},
+		{name: "all trailing breaks removed", fileName: "out.golden", content: "a\r\n\r\n\n", expected: "a", expectChanges: []string{changeRemovedNewline}},
+		{name: "no newline untouched", fileName: "out.golden", content: "a", expected: "a"},
+		{name: "only line breaks untouched", fileName: "out.golden", content: "\n\n", expected: "\n\n"},
+		{name: "dry run", fileName: "out.golden", content: "a\n", dryRun: true, expected: "a\n", expectChanges: []string{changeRemovedNewline}},
+		{name: "other files still get a newline", fileName: "main.go", content: "a", expected: "a\n", expectChanges: []string{changeAddedNewline}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), tt.fileName)
+			_ = os.WriteFile(filePath, []byte(tt.content), 0o644)
+
+			result := FileResult{Path: filePath}
+			options := processOptions{policies: none, dryRun: tt.dryRun}
+			if err := addNewlineIfNeeded(&mockLogger{}, filePath, options, &result); err != nil {
+				t.Fatalf("addNewlineIfNeeded() error = %v", err)
+			}
+			content, _ := os.ReadFile(filePath)
+			if string(content) != tt.expected {
+				t.Errorf("File content = %q, want %q", content, tt.expected)
+			}
+			if strings.Join(result.Changes, "|") != strings.Join(tt.expectChanges, "|") {
+				t.Errorf("Changes = %v, want %v", result.Changes, tt.expectChanges)
+			}
+		})
+	}
+}
+
+func TestNewBlankLineFixer(t *testing.T) {
+	on, zero, two := true, 0, 2
+
+	tests := []struct {
+		name              string
+		policy            cli.Policy
+		expectNil         bool
+		expectTrimLeading bool
+		expectMaxRun      int
+	}{
+		{name: "default policy", policy: defaultPolicy, expectNil: true},
+		{name: "leading", policy: cli.Policy{TrimLeadingBlankLines: &on}, expectTrimLeading: true, expectMaxRun: -1},
+		{name: "max run", policy: cli.Policy{MaxBlankLines: &two}, expectMaxRun: 2},
+		{name: "no blank lines", policy: cli.Policy{MaxBlankLines: &zero}, expectMaxRun: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixer := newBlankLineFixer("a.txt", tt.policy)
+			if (fixer == nil) != tt.expectNil {
+				t.Fatalf("newBlankLineFixer() = %v, expectNil %v", fixer, tt.expectNil)
+			}
+			if fixer != nil && (fixer.trimLeading != tt.expectTrimLeading || fixer.maxRun != tt.expectMaxRun) {
+				t.Errorf("newBlankLineFixer() = %+v", fixer)
+			}
+		})
+	}
+}
+
+func TestBlankLineFixerFix(t *testing.T) {
+	tests := []struct {
+		name         string
+		fixer        blankLineFixer
+		content      string
+		expected     string
+		expectChange string
+	}{
+		{name: "clean", fixer: blankLineFixer{trimLeading: true, maxRun: 1}, content: "a\n\nb\n", expectChange: ""},
+		{
+			name:         "leading blank lines",
+			fixer:        blankLineFixer{trimLeading: true, maxRun: -1},
+			content:      "\n\r\n\ta\n\n\n\nb\n",
+			expected:     "\ta\n\n\n\nb\n",
+			expectChange: "removed 2 leading blank line(s)",
+		},
+		{
+			name:         "long runs",
+			fixer:        blankLineFixer{maxRun: 1},
+			content:      "a\n\n\n\nb\n\n\nc\n",
+			expected:     "a\n\nb\n\nc\n",
+			expectChange: "removed 3 blank line(s) beyond runs of 1",
+		},
+		{
+			name:         "whitespace-only lines are not blank",
+			fixer:        blankLineFixer{trimLeading: true, maxRun: 0},
+			content:      " \na\n \n\t\nb\n",
+			expectChange: "",
+		},
+		{
+			name:         "whitespace-only line ends the run like exactly-one",
+			fixer:        blankLineFixer{maxRun: 0},
+			content:      "x\n \n\n",
+			expected:     "x\n \n",
+			expectChange: "removed 1 blank line(s) beyond runs of 0",
+		},
+		{
+			name:         "leading lines kept but collapsed",
+			fixer:        blankLineFixer{maxRun: 1},
+			content:      "\n\n\na\n",
+			expected:     "\na\n",
+			expectChange: "removed 2 blank line(s) beyond runs of 1",
+		},
+		{
+			name:         "no blank lines at all",
+			fixer:        blankLineFixer{maxRun: 0},
+			content:      "a\r\n\r\nb\r\n",
+			expected:     "a\r\nb\r\n",
+			expectChange: "removed 1 blank line(s) beyond runs of 0",
+		},
+		{
+			name:         "both",
+			fixer:        blankLineFixer{trimLeading: true, maxRun: 2},
+			content:      "\na\n\n\n\nb",
+			expected:     "a\n\n\nb",
+			expectChange: "removed 1 leading blank line(s), removed 1 blank line(s) beyond runs of 2",
+		},
+		{
+			name:         "byte order mark kept",
+			fixer:        blankLineFixer{trimLeading: true, maxRun: -1},
+			content:      "\ufeff\n\na\n",
+			expected:     "\ufeffa\n",
+			expectChange: "removed 2 leading blank line(s)",
+		},
+		{name: "last line without line break is not blank", fixer: blankLineFixer{maxRun: 0}, content: "a\n  ", expectChange: ""},
+		{name: "only blank lines untouched", fixer: blankLineFixer{trimLeading: true, maxRun: 0}, content: "\n\r\n\n", expectChange: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, change := tt.fixer.fix([]byte(tt.content))
+			if change != tt.expectChange {
+				t.Errorf("fix() change = %q, want %q", change, tt.expectChange)
+			}
+			if tt.expectChange != "" && string(fixed) != tt.expected {
+				t.Errorf("fix() = %q, want %q", fixed, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBlankLineFixerKeepsLiterals(t *testing.T) {
+	tests := []struct {
+		name         string
+		filePath     string
+		content      string
+		expected     string
+		expectChange string
+	}{
+		{
+			name:         "go raw string",
+			filePath:     "main.go",
+			content:      "x := `a\n\n\nb`\n\n\ny := 1\n",
+			expected:     "x := `a\n\n\nb`\n\ny := 1\n",
+			expectChange: "removed 1 blank line(s) beyond runs of 1",
+		},
+		{
+			name:         "python triple-quoted string",
+			filePath:     "app.py",
+			content:      "s = \"\"\"\n\n\n\"\"\"\n",
+			expectChange: "",
+		},
+		{
+			name:         "shell heredoc",
+			filePath:     "run.sh",
+			content:      "cat <<EOF\na\n\n\nb\nEOF\n\n\necho\n",
+			expected:     "cat <<EOF\na\n\n\nb\nEOF\n\necho\n",
+			expectChange: "removed 1 blank line(s) beyond runs of 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixer := newBlankLineFixer(tt.filePath, cli.Policy{MaxBlankLines: ptr(1)})
+			fixed, change := fixer.fix([]byte(tt.content))
+			if change != tt.expectChange {
+				t.Errorf("fix() change = %q, want %q", change, tt.expectChange)
+			}
+			if tt.expectChange != "" && string(fixed) != tt.expected {
+				t.Errorf("fix() = %q, want %q", fixed, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAddNewlineIfNeededBlankLines(t *testing.T) {
+	on, one := true, 1
+	options := processOptions{
+		policies: newPolicyResolver(cli.PolicySet{Policy: cli.Policy{
+			FinalNewline:          cli.FinalNewlineExactlyOne,
+			TrimLeadingBlankLines: &on,
+			MaxBlankLines:         &one,
+		}}),
+	}
+	filePath := filepath.Join(t.TempDir(), "file.txt")
+	_ = os.WriteFile(filePath, []byte("\n\na\n\n\n\nb\n\n\n"), 0o644)
+
+	result := FileResult{Path: filePath}
+	if err := addNewlineIfNeeded(&mockLogger{}, filePath, options, &result); err != nil {
+		t.Fatalf("addNewlineIfNeeded() error = %v", err)
+	}
+	if content, _ := os.ReadFile(filePath); string(content) != "a\n\nb\n" {
+		t.Errorf("File content = %q, want %q", content, "a\n\nb\n")
+	}
+	expectedChanges := "removed 2 leading blank line(s), removed 3 blank line(s) beyond runs of 1|removed 1 trailing blank line(s)"
+	if strings.Join(result.Changes, "|") != expectedChanges {
+		t.Errorf("Changes = %v, want %v", result.Changes, expectedChanges)
+	}
+}
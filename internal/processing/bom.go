@@ -0,0 +1,72 @@
+package processing
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+// Descriptions of byte order mark changes
+const (
+	// changeRemovedBOM records that a UTF-8 byte order mark was removed
+	changeRemovedBOM = "removed byte order mark"
+	// changeAddedBOM records that a UTF-8 byte order mark was added
+	changeAddedBOM = "added byte order mark"
+)
+
+// utf8BOM is the UTF-8 encoding of the byte order mark U+FEFF
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// bomFixer adds or removes the UTF-8 byte order mark at the start of a file
+type bomFixer struct {
+	// require adds a missing byte order mark; otherwise a present one is removed
+	require bool
+}
+
+// newBOMFixer creates a byte order mark fixer for the policy, or returns nil
+// when the policy leaves the byte order mark alone
+func newBOMFixer(policy string) *bomFixer {
+	switch policy {
+	case cli.BOMStrip:
+		return &bomFixer{require: false}
+	case cli.BOMRequire:
+		return &bomFixer{require: true}
+	}
+	return nil
+}
+
+// fix adds or removes the byte order mark
+func (bf *bomFixer) fix(content []byte) ([]byte, string) {
+	hasBOM := bytes.HasPrefix(content, utf8BOM)
+	switch {
+	case bf.require && !hasBOM:
+		return append(bytes.Clone(utf8BOM), content...), changeAddedBOM
+	case !bf.require && hasBOM:
+		return content[len(utf8BOM):], changeRemovedBOM
+	}
+	return nil, ""
+}
+
+// currentBOMPolicy returns the byte order mark policy that keeps a file the way it is
+// on disk: require when it starts with a byte order mark, strip when it does not,
+// and preserve when it is missing or empty
+func currentBOMPolicy(filePath string) string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return cli.BOMPreserve
+	}
+	defer file.Close()
+
+	head := make([]byte, len(utf8BOM))
+	n, _ := io.ReadFull(file, head)
+	switch {
+	case n == 0:
+		return cli.BOMPreserve
+	case bytes.Equal(head[:n], utf8BOM):
+		return cli.BOMRequire
+	default:
+		return cli.BOMStrip
+	}
+}
@@ -0,0 +1,136 @@
+package processing
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/toolinput"
+)
+
+func TestBOMFixerFix(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		content      string
+		expected     string
+		expectChange string
+	}{
+		{name: "strip present", policy: cli.BOMStrip, content: "\ufeffpackage main\n", expected: "package main\n", expectChange: changeRemovedBOM},
+		{name: "strip absent", policy: cli.BOMStrip, content: "package main\n", expectChange: ""},
+		{name: "require absent", policy: cli.BOMRequire, content: "Write-Host hi\r\n", expected: "\ufeffWrite-Host hi\r\n", expectChange: changeAddedBOM},
+		{name: "require present", policy: cli.BOMRequire, content: "\ufeffWrite-Host hi\r\n", expectChange: ""},
+		{name: "BOM in the middle is not touched", policy: cli.BOMStrip, content: "a\ufeffb\n", expectChange: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, change := newBOMFixer(tt.policy).fix([]byte(tt.content))
+			if change != tt.expectChange {
+				t.Errorf("fix() change = %q, want %q", change, tt.expectChange)
+			}
+			if change != "" && string(fixed) != tt.expected {
+				t.Errorf("fix() = %q, want %q", fixed, tt.expected)
+			}
+		})
+	}
+
+	if fixer := newBOMFixer(cli.BOMPreserve); fixer != nil {
+		t.Errorf("newBOMFixer(preserve) = %v, want nil", fixer)
+	}
+}
+
+func TestCurrentBOMPolicy(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		content  *string
+		expected string
+	}{
+		{name: "missing file", content: nil, expected: cli.BOMPreserve},
+		{name: "empty file", content: new(string), expected: cli.BOMPreserve},
+		{name: "with BOM", content: ptr("\ufeffdata"), expected: cli.BOMRequire},
+		{name: "without BOM", content: ptr("data"), expected: cli.BOMStrip},
+		{name: "shorter than a BOM", content: ptr("a"), expected: cli.BOMStrip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(dir, tt.name)
+			if tt.content != nil {
+				_ = os.WriteFile(filePath, []byte(*tt.content), 0o644)
+			}
+			if result := currentBOMPolicy(filePath); result != tt.expected {
+				t.Errorf("currentBOMPolicy() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAddNewlineIfNeededBOM(t *testing.T) {
+	options := processOptions{
+		policies: newPolicyResolver(cli.PolicySet{
+			Policy: cli.Policy{BOM: cli.BOMStrip},
+			Rules:  []cli.Rule{{Pattern: "*.ps1", Policy: cli.Policy{BOM: cli.BOMRequire}}},
+		}),
+	}
+
+	tests := []struct {
+		name          string
+		fileName      string
+		content       string
+		expected      string
+		expectChanges []string
+	}{
+		{name: "BOM stripped with newline added", fileName: "main.go", content: "\ufeffpackage main", expected: "package main\n", expectChanges: []string{changeRemovedBOM, changeAddedNewline}},
+		{name: "BOM required", fileName: "script.ps1", content: "Write-Host hi\r\n", expected: "\ufeffWrite-Host hi\r\n", expectChanges: []string{changeAddedBOM}},
+		{name: "already compliant", fileName: "main.go", content: "package main\n", expected: "package main\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), tt.fileName)
+			_ = os.WriteFile(filePath, []byte(tt.content), 0o644)
+
+			result := FileResult{Path: filePath}
+			if err := addNewlineIfNeeded(&mockLogger{}, filePath, options, &result); err != nil {
+				t.Fatalf("addNewlineIfNeeded() error = %v", err)
+			}
+			content, _ := os.ReadFile(filePath)
+			if string(content) != tt.expected {
+				t.Errorf("File content = %q, want %q", content, tt.expected)
+			}
+			if !reflect.DeepEqual(result.Changes, tt.expectChanges) {
+				t.Errorf("Changes = %v, want %v", result.Changes, tt.expectChanges)
+			}
+		})
+	}
+}
+
+func TestBuildPreToolUseResponsePreservesBOM(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "Program.cs")
+	_ = os.WriteFile(filePath, []byte("\ufeffclass A {}\n"), 0o644)
+
+	input, _ := json.Marshal(map[string]string{"file_path": filePath, "content": "class B {}\n"})
+	hook := &toolinput.HookInput{ToolName: "Write", ToolInput: input}
+
+	resp, err := buildPreToolUseResponse(&mockLogger{}, hook, filePath, processOptions{})
+	if err != nil || resp == nil {
+		t.Fatalf("buildPreToolUseResponse() = %v, %v", resp, err)
+	}
+	var updated toolinput.WriteInput
+	if err := json.Unmarshal(resp.HookSpecificOutput.UpdatedInput, &updated); err != nil {
+		t.Fatalf("UpdatedInput is not valid JSON: %v", err)
+	}
+	if updated.Content != "\ufeffclass B {}\n" {
+		t.Errorf("Content = %q, want the byte order mark restored", updated.Content)
+	}
+}
+
+// ptr returns a pointer to a copy of value
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,68 @@
+package processing
+
+import (
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/logging"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// changeTranscoded records that a file written as UTF-8 was converted to its declared charset
+const changeTranscoded = "transcoded UTF-8 to %s"
+
+// skipUTF8 records that a file of a declared charset holds UTF-8 text instead
+const skipUTF8 = "UTF-8 instead of %s"
+
+// charsetEncodings maps the values of the charset policy to their encodings
+var charsetEncodings = map[string]*textEncoding{
+	cli.CharsetShiftJIS: {name: "Shift_JIS", unitSize: 1, codec: japanese.ShiftJIS},
+	cli.CharsetEUCJP:    {name: "EUC-JP", unitSize: 1, codec: japanese.EUCJP},
+	cli.CharsetLatin1:   {name: "Latin-1", unitSize: 1, codec: charmap.ISO8859_1},
+}
+
+// isASCII reports whether content holds only ASCII characters, which read the same
+// in UTF-8 and in every supported charset
+func isASCII(content []byte) bool {
+	for _, b := range content {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// fixCharsetTextIfNeeded applies the policy to a file declared to be in a legacy
+// charset by decoding it, fixing the text and encoding it back. A file holding
+// UTF-8 text instead is converted to the charset when the policy transcodes UTF-8
+// and skipped otherwise, and a file that does not decode in the charset is skipped,
+// so that files whose encoding cannot be established are never modified.
+func fixCharsetTextIfNeeded(logger logging.Logger, filePath string, encoding *textEncoding, policy cli.Policy, options processOptions, result *FileResult) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Multibyte UTF-8 is checked first, since its bytes may also decode in the charset
+	var changes []string
+	text := string(data)
+	switch {
+	case !isASCII(data) && utf8.Valid(data):
+		if !enabled(policy.TranscodeUTF8) {
+			logger.Debug(fmt.Sprintf("│ Skipping: declared %s but holds UTF-8 text", encoding.name))
+			result.Skipped = fmt.Sprintf(skipUTF8, encoding.name)
+			return nil
+		}
+		changes = append(changes, fmt.Sprintf(changeTranscoded, encoding.name))
+	default:
+		if text, err = encoding.decode(data); err != nil {
+			logger.Debug(fmt.Sprintf("│ Skipping %s file: %v", encoding.name, err))
+			result.Skipped = fmt.Sprintf(skipUndecodable, encoding.name)
+			return nil
+		}
+	}
+	return fixDecodedText(logger, filePath, encoding, nil, text, changes, policy, options, result)
+}
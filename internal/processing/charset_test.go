@@ -0,0 +1,169 @@
+package processing
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+func TestIsASCII(t *testing.T) {
+	if !isASCII([]byte("hello\r\n")) || !isASCII(nil) {
+		t.Error("isASCII() = false for ASCII content")
+	}
+	if isASCII([]byte("café")) || isASCII([]byte{'a', 0x82, 0xa0}) {
+		t.Error("isASCII() = true for content with high bytes")
+	}
+}
+
+func TestCharsetEncodingsDecode(t *testing.T) {
+	shiftJIS := charsetEncodings[cli.CharsetShiftJIS]
+	eucJP := charsetEncodings[cli.CharsetEUCJP]
+	latin1 := charsetEncodings[cli.CharsetLatin1]
+
+	tests := []struct {
+		name      string
+		encoding  *textEncoding
+		content   []byte
+		expected  string
+		expectErr bool
+	}{
+		{name: "Shift_JIS", encoding: shiftJIS, content: []byte{0x82, 0xa0, '\n'}, expected: "あ\n"},
+		{name: "Shift_JIS backslash in second byte", encoding: shiftJIS, content: []byte{0x95, 0x5c}, expected: "表"},
+		{name: "EUC-JP", encoding: eucJP, content: []byte{0xa4, 0xa2, '\n'}, expected: "あ\n"},
+		{name: "Latin-1", encoding: latin1, content: []byte("caf\xe9\n"), expected: "café\n"},
+		{name: "invalid Shift_JIS", encoding: shiftJIS, content: []byte{0x81, 0x20}, expectErr: true},
+		{name: "truncated EUC-JP", encoding: eucJP, content: []byte{'a', 0xa4}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.encoding.decode(tt.content)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("decode() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if err == nil && result != tt.expected {
+				t.Errorf("decode() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+
+	if _, err := shiftJIS.encode("😀"); err == nil {
+		t.Error("encode() should fail for characters Shift_JIS cannot represent")
+	}
+}
+
+func TestAddNewlineIfNeededCharsets(t *testing.T) {
+	on := true
+	shiftJIS := charsetEncodings[cli.CharsetShiftJIS]
+	declare := func(policy cli.Policy) processOptions {
+		return processOptions{policies: newPolicyResolver(cli.PolicySet{Rules: []cli.Rule{{Pattern: "*.txt", Policy: policy}}})}
+	}
+	sjis := declare(cli.Policy{Charset: cli.CharsetShiftJIS})
+	transcode := declare(cli.Policy{Charset: cli.CharsetShiftJIS, TranscodeUTF8: &on})
+
+	tests := []struct {
+		name          string
+		content       []byte
+		options       processOptions
+		expected      []byte
+		expectChanges []string
+		expectSkipped string
+	}{
+		{
+			name:          "Shift_JIS",
+			content:       mustEncode(shiftJIS, "日本語\nテキスト"),
+			options:       sjis,
+			expected:      mustEncode(shiftJIS, "日本語\nテキスト\n"),
+			expectChanges: []string{changeAddedNewline},
+		},
+		{
+			name:          "EUC-JP trailing whitespace",
+			content:       mustEncode(charsetEncodings[cli.CharsetEUCJP], "日本語  \n"),
+			options:       declare(cli.Policy{Charset: cli.CharsetEUCJP, TrimTrailingWhitespace: &on}),
+			expected:      mustEncode(charsetEncodings[cli.CharsetEUCJP], "日本語\n"),
+			expectChanges: []string{"trimmed trailing whitespace on 1 line(s)"},
+		},
+		{
+			name:          "Latin-1",
+			content:       []byte("caf\xe9"),
+			options:       declare(cli.Policy{Charset: cli.CharsetLatin1}),
+			expected:      []byte("caf\xe9\n"),
+			expectChanges: []string{changeAddedNewline},
+		},
+		{
+			name:          "ASCII",
+			content:       []byte("hello"),
+			options:       sjis,
+			expected:      []byte("hello\n"),
+			expectChanges: []string{changeAddedNewline},
+		},
+		{
+			name:          "UTF-8 left alone",
+			content:       []byte("日本語"),
+			options:       sjis,
+			expected:      []byte("日本語"),
+			expectSkipped: "UTF-8 instead of Shift_JIS",
+		},
+		{
+			name:          "UTF-8 transcoded",
+			content:       []byte("日本語"),
+			options:       transcode,
+			expected:      mustEncode(shiftJIS, "日本語\n"),
+			expectChanges: []string{"transcoded UTF-8 to Shift_JIS", changeAddedNewline},
+		},
+		{
+			name:          "UTF-8 transcoded in dry run",
+			content:       []byte("日本語\n"),
+			options:       processOptions{policies: transcode.policies, dryRun: true},
+			expected:      []byte("日本語\n"),
+			expectChanges: []string{"transcoded UTF-8 to Shift_JIS"},
+		},
+		{
+			name:          "UTF-8 not representable",
+			content:       []byte("日本語 😀\n"),
+			options:       transcode,
+			expected:      []byte("日本語 😀\n"),
+			expectSkipped: "not representable in Shift_JIS",
+		},
+		{
+			name:          "invalid Shift_JIS",
+			content:       []byte{'a', 0x81, 0x20, 0xff, 0xfe, '\n'},
+			options:       sjis,
+			expected:      []byte{'a', 0x81, 0x20, 0xff, 0xfe, '\n'},
+			expectSkipped: "not valid Shift_JIS",
+		},
+		{
+			name:          "UTF-16 with BOM",
+			content:       append([]byte{0xff, 0xfe}, mustEncode(encodingUTF16LE, "a")...),
+			options:       sjis,
+			expected:      append([]byte{0xff, 0xfe}, mustEncode(encodingUTF16LE, "a")...),
+			expectSkipped: "not valid Shift_JIS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file.txt")
+			_ = os.WriteFile(filePath, tt.content, 0o644)
+
+			result := FileResult{Path: filePath}
+			if err := addNewlineIfNeeded(&mockLogger{}, filePath, tt.options, &result); err != nil {
+				t.Fatalf("addNewlineIfNeeded() error = %v", err)
+			}
+			content, _ := os.ReadFile(filePath)
+			if !bytes.Equal(content, tt.expected) {
+				t.Errorf("File content = %q, want %q", content, tt.expected)
+			}
+			if !reflect.DeepEqual(result.Changes, tt.expectChanges) {
+				t.Errorf("Changes = %v, want %v", result.Changes, tt.expectChanges)
+			}
+			if result.Skipped != tt.expectSkipped {
+				t.Errorf("Skipped = %q, want %q", result.Skipped, tt.expectSkipped)
+			}
+		})
+	}
+}
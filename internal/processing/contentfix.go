@@ -0,0 +1,154 @@
+package processing
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/logging"
+)
+
+// contentFixer rewrites the content of a file as a whole
+type contentFixer interface {
+	// fix returns the fixed content and a description of the change,
+	// or "" when the content needs no change
+	fix(content []byte) ([]byte, string)
+}
+
+// contentScanner finds problems in the content of a file that are left for the user to fix
+type contentScanner interface {
+	// scan returns the problems found, as "line:column: description"
+	scan(content []byte) []string
+}
+
+// newContentFixers returns the fixers the policy enables for a file, in the order they run
+func newContentFixers(filePath string, policy cli.Policy) []contentFixer {
+	var fixers []contentFixer
+	if fixer := newBOMFixer(policy.BOM); fixer != nil {
+		fixers = append(fixers, fixer)
+	}
+	if fixer := newControlCharacterFixer(policy.ControlCharacters); fixer != nil {
+		fixers = append(fixers, fixer)
+	}
+	if fixer := newInvisibleCharacterFixer(policy); fixer != nil {
+		fixers = append(fixers, fixer)
+	}
+	if fixer := newLineEndingFixer(policy.EndOfLine); fixer != nil {
+		fixers = append(fixers, fixer)
+	}
+	if fixer := newIndentFixer(filePath, policy); fixer != nil {
+		fixers = append(fixers, fixer)
+	}
+	if enabled(policy.TrimTrailingWhitespace) {
+		if fixer := newTrailingWhitespaceFixer(filePath); fixer != nil {
+			fixers = append(fixers, fixer)
+		}
+	}
+	if fixer := newBlankLineFixer(filePath, policy); fixer != nil {
+		fixers = append(fixers, fixer)
+	}
+	return fixers
+}
+
+// newContentScanners returns the scanners the policy enables
+func newContentScanners(policy cli.Policy) []contentScanner {
+	var scanners []contentScanner
+	if scanner := newControlCharacterScanner(policy.ControlCharacters); scanner != nil {
+		scanners = append(scanners, scanner)
+	}
+	if scanner := newConfusableScanner(policy); scanner != nil {
+		scanners = append(scanners, scanner)
+	}
+	return scanners
+}
+
+// applyContentFixers runs the fixers over content in order, returning the fixed
+// content and the changes made
+func applyContentFixers(fixers []contentFixer, content []byte) ([]byte, []string) {
+	var changes []string
+	for _, fixer := range fixers {
+		fixed, change := fixer.fix(content)
+		if change == "" {
+			continue
+		}
+		content = fixed
+		changes = append(changes, change)
+	}
+	return content, changes
+}
+
+// fixTextContent applies the whole policy to content held in memory: the content
+// fixers followed by the final newline policy. It returns the fixed content and
+// the changes made.
+func fixTextContent(filePath string, policy cli.Policy, content string) (string, []string) {
+	fixed, changes := applyContentFixers(newContentFixers(filePath, policy), []byte(content))
+	content = string(fixed)
+
+	switch policy.FinalNewline {
+	case cli.FinalNewlineNone:
+		if trimmed := strings.TrimRight(content, "\r\n"); trimmed != "" && trimmed != content {
+			content = trimmed
+			changes = append(changes, changeRemovedNewline)
+		}
+		return content, changes
+	case cli.FinalNewlineExactlyOne:
+		var removed int
+		if content, removed = collapseTrailingBlankLinesInContent(content); removed > 0 {
+			changes = append(changes, fmt.Sprintf(changeRemovedBlankLines, removed))
+		}
+	}
+
+	if ending := missingEndingFromContent([]byte(content)); ending != "" {
+		content += ending
+		changes = append(changes, changeAddedNewline)
+	}
+	return content, changes
+}
+
+// scanContent records the problems the policy reports rather than fixes in content,
+// as it reads once fixed
+func scanContent(logger logging.Logger, policy cli.Policy, content []byte, result *FileResult) {
+	for _, scanner := range newContentScanners(policy) {
+		findings := scanner.scan(content)
+		for _, finding := range findings {
+			logger.Debug(fmt.Sprintf("│ Found %s", finding))
+		}
+		result.Findings = append(result.Findings, findings...)
+	}
+}
+
+// fixContentIfNeeded runs the content fixers the policy enables for a file and
+// writes the file once, only when one of them changed the content
+func fixContentIfNeeded(logger logging.Logger, filePath string, policy cli.Policy, options processOptions, result *FileResult) error {
+	fixers := newContentFixers(filePath, policy)
+	if len(fixers) == 0 && len(newContentScanners(policy)) == 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	fixed, changes := applyContentFixers(fixers, data)
+	scanContent(logger, policy, fixed, result)
+	if len(changes) == 0 {
+		logger.Debug("│ Content already follows the policy")
+		return nil
+	}
+
+	if options.dryRun {
+		logger.Debug(fmt.Sprintf("│ Content needs fixing: %v (dry run, not modified)", changes))
+		result.Changes = append(result.Changes, changes...)
+		return nil
+	}
+
+	logger.Debug(fmt.Sprintf("│ Fixing content: %v", changes))
+	if err := os.WriteFile(filePath, fixed, filePermission); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	result.Changes = append(result.Changes, changes...)
+	logger.Info(fmt.Sprintf("Fixed content of %s", filePath))
+	return nil
+}
@@ -0,0 +1,141 @@
+package processing
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+// upperCaseFixer is a content fixer for tests that upper-cases "x"
+type upperCaseFixer struct{}
+
+func (ucf *upperCaseFixer) fix(content []byte) ([]byte, string) {
+	fixed := []byte(string(content))
+	changed := false
+	for i, b := range fixed {
+		if b == 'x' {
+			fixed[i] = 'X'
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, ""
+	}
+	return fixed, "upper-cased x"
+}
+
+func TestNewContentFixers(t *testing.T) {
+	on, off := true, false
+
+	tests := []struct {
+		name     string
+		filePath string
+		policy   cli.Policy
+		expected int
+	}{
+		{name: "default policy", filePath: "main.go", policy: defaultPolicy, expected: 0},
+		{name: "whitespace off", filePath: "main.go", policy: cli.Policy{TrimTrailingWhitespace: &off}, expected: 0},
+		{name: "whitespace on", filePath: "main.go", policy: cli.Policy{TrimTrailingWhitespace: &on}, expected: 1},
+		{name: "whitespace on for patch", filePath: "fix.patch", policy: cli.Policy{TrimTrailingWhitespace: &on}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := newContentFixers(tt.filePath, tt.policy); len(result) != tt.expected {
+				t.Errorf("newContentFixers() returned %d fixers, want %d", len(result), tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplyContentFixers(t *testing.T) {
+	fixers := []contentFixer{&trailingWhitespaceFixer{}, &upperCaseFixer{}}
+
+	fixed, changes := applyContentFixers(fixers, []byte("x \ny\n"))
+	if string(fixed) != "X\ny\n" {
+		t.Errorf("applyContentFixers() = %q, want %q", fixed, "X\ny\n")
+	}
+	expectedChanges := []string{"trimmed trailing whitespace on 1 line(s)", "upper-cased x"}
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("changes = %v, want %v", changes, expectedChanges)
+	}
+
+	fixed, changes = applyContentFixers(fixers, []byte("y\n"))
+	if string(fixed) != "y\n" || changes != nil {
+		t.Errorf("applyContentFixers() = %q, %v, want unchanged content and no changes", fixed, changes)
+	}
+}
+
+func TestFixContentIfNeeded(t *testing.T) {
+	on := true
+	trim := cli.Policy{TrimTrailingWhitespace: &on}
+
+	tests := []struct {
+		name          string
+		content       string
+		policy        cli.Policy
+		dryRun        bool
+		expected      string
+		expectChanges []string
+	}{
+		{name: "no fixers", content: "a \n", policy: defaultPolicy, expected: "a \n"},
+		{name: "fixed", content: "a \n", policy: trim, expected: "a\n", expectChanges: []string{"trimmed trailing whitespace on 1 line(s)"}},
+		{name: "already clean", content: "a\n", policy: trim, expected: "a\n"},
+		{name: "dry run", content: "a \n", policy: trim, dryRun: true, expected: "a \n", expectChanges: []string{"trimmed trailing whitespace on 1 line(s)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file.txt")
+			_ = os.WriteFile(filePath, []byte(tt.content), 0o644)
+
+			result := FileResult{Path: filePath}
+			if err := fixContentIfNeeded(&mockLogger{}, filePath, tt.policy, processOptions{dryRun: tt.dryRun}, &result); err != nil {
+				t.Fatalf("fixContentIfNeeded() error = %v", err)
+			}
+			content, _ := os.ReadFile(filePath)
+			if string(content) != tt.expected {
+				t.Errorf("File content = %q, want %q", content, tt.expected)
+			}
+			if !reflect.DeepEqual(result.Changes, tt.expectChanges) {
+				t.Errorf("Changes = %v, want %v", result.Changes, tt.expectChanges)
+			}
+		})
+	}
+}
+
+func TestAddNewlineIfNeededTrimsWhitespace(t *testing.T) {
+	on := true
+	options := processOptions{
+		policies: newPolicyResolver(cli.PolicySet{Rules: []cli.Rule{{Pattern: "*.go", Policy: cli.Policy{TrimTrailingWhitespace: &on}}}}),
+	}
+	dir := t.TempDir()
+	goFile := filepath.Join(dir, "main.go")
+	textFile := filepath.Join(dir, "notes.txt")
+	_ = os.WriteFile(goFile, []byte("package main \nfunc main() {}\t"), 0o644)
+	_ = os.WriteFile(textFile, []byte("notes \n"), 0o644)
+
+	result := FileResult{Path: goFile}
+	if err := addNewlineIfNeeded(&mockLogger{}, goFile, options, &result); err != nil {
+		t.Fatalf("addNewlineIfNeeded() error = %v", err)
+	}
+	content, _ := os.ReadFile(goFile)
+	if string(content) != "package main\nfunc main() {}\n" {
+		t.Errorf("File content = %q", content)
+	}
+	expectedChanges := []string{"trimmed trailing whitespace on 2 line(s)", changeAddedNewline}
+	if !reflect.DeepEqual(result.Changes, expectedChanges) {
+		t.Errorf("Changes = %v, want %v", result.Changes, expectedChanges)
+	}
+
+	result = FileResult{Path: textFile}
+	if err := addNewlineIfNeeded(&mockLogger{}, textFile, options, &result); err != nil {
+		t.Fatalf("addNewlineIfNeeded() error = %v", err)
+	}
+	if content, _ := os.ReadFile(textFile); string(content) != "notes \n" || result.Modified() {
+		t.Errorf("File without the rule should be untouched, got %q, %v", content, result.Changes)
+	}
+}
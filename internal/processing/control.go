@@ -0,0 +1,169 @@
+package processing
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+// changeStrippedControl records that escape sequences and control characters were removed
+const changeStrippedControl = "stripped %d escape sequence(s) and control character(s)"
+
+// Bytes that start or end escape sequences
+const (
+	escapeByte = 0x1b
+	bellByte   = 0x07
+	deleteByte = 0x7f
+)
+
+// isStrayControl reports whether b is a C0 control character or DEL that has no place
+// in text; tab, LF, CR and form feed are kept
+func isStrayControl(b byte) bool {
+	switch b {
+	case '\t', '\n', '\r', '\f':
+		return false
+	}
+	return b < 0x20 || b == deleteByte
+}
+
+// escapeSequenceLength returns the length of the ANSI escape sequence at the start
+// of content, which begins with ESC: a control sequence (ESC [ ... final byte),
+// an operating system command (ESC ] ... BEL or ESC \) or a short escape (ESC and
+// intermediate bytes followed by a final byte). Unterminated sequences end at the
+// line break, and a lone ESC has length 1.
+func escapeSequenceLength(content []byte) int {
+	if len(content) < 2 {
+		return 1
+	}
+	switch content[1] {
+	case '[':
+		for i := 2; i < len(content); i++ {
+			switch b := content[i]; {
+			case b >= 0x40 && b <= 0x7e:
+				return i + 1
+			case b < 0x20 || b > 0x3f:
+				return i
+			}
+		}
+		return len(content)
+	case ']':
+		for i := 2; i < len(content); i++ {
+			switch {
+			case content[i] == bellByte:
+				return i + 1
+			case content[i] == escapeByte && i+1 < len(content) && content[i+1] == '\\':
+				return i + 2
+			case content[i] == newlineByte:
+				return i
+			}
+		}
+		return len(content)
+	}
+
+	i := 1
+	for i < len(content) && content[i] >= 0x20 && content[i] <= 0x2f {
+		i++
+	}
+	if i < len(content) && content[i] >= 0x30 && content[i] <= 0x7e {
+		return i + 1
+	}
+	return 1
+}
+
+// findControl returns the position and length of the first escape sequence or stray
+// control character in content, or -1 when there is none
+func findControl(content []byte) (int, int) {
+	for i, b := range content {
+		if b == escapeByte {
+			return i, escapeSequenceLength(content[i:])
+		}
+		if isStrayControl(b) {
+			return i, 1
+		}
+	}
+	return -1, 0
+}
+
+// controlCharacterFixer removes ANSI escape sequences and stray control characters
+type controlCharacterFixer struct{}
+
+// newControlCharacterFixer creates a control character fixer for the policy, or
+// returns nil when the policy does not strip control characters
+func newControlCharacterFixer(policy string) *controlCharacterFixer {
+	if policy != cli.ControlCharactersStrip {
+		return nil
+	}
+	return &controlCharacterFixer{}
+}
+
+// fix removes every escape sequence and stray control character
+func (ccf *controlCharacterFixer) fix(content []byte) ([]byte, string) {
+	var fixed bytes.Buffer
+	stripped := 0
+	for {
+		start, length := findControl(content)
+		if start < 0 {
+			break
+		}
+		fixed.Write(content[:start])
+		content = content[start+length:]
+		stripped++
+	}
+
+	if stripped == 0 {
+		return nil, ""
+	}
+	fixed.Write(content)
+	return fixed.Bytes(), fmt.Sprintf(changeStrippedControl, stripped)
+}
+
+// controlCharacterScanner reports ANSI escape sequences and stray control characters
+type controlCharacterScanner struct{}
+
+// newControlCharacterScanner creates a control character scanner for the policy, or
+// returns nil when the policy does not report control characters
+func newControlCharacterScanner(policy string) *controlCharacterScanner {
+	if policy != cli.ControlCharactersReport {
+		return nil
+	}
+	return &controlCharacterScanner{}
+}
+
+// scan returns a finding for each escape sequence and stray control character,
+// with 1-based positions counted in characters
+func (ccs *controlCharacterScanner) scan(content []byte) []string {
+	var findings []string
+	line, column := 1, 1
+	for {
+		start, length := findControl(content)
+		if start < 0 {
+			return findings
+		}
+		line, column = advancePosition(line, column, content[:start])
+		control := content[start : start+length]
+		description := fmt.Sprintf("control character %U", rune(control[0]))
+		if control[0] == escapeByte && length > 1 {
+			description = fmt.Sprintf("ANSI escape sequence %q", control)
+		}
+		findings = append(findings, fmt.Sprintf("%d:%d: %s", line, column, description))
+		line, column = advancePosition(line, column, control)
+		content = content[start+length:]
+	}
+}
+
+// advancePosition returns the line and column reached after text that starts at
+// the given line and column
+func advancePosition(line, column int, text []byte) (int, int) {
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		text = text[size:]
+		if r == '\n' {
+			line, column = line+1, 1
+			continue
+		}
+		column++
+	}
+	return line, column
+}
@@ -0,0 +1,187 @@
+package processing
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+func TestIsStrayControl(t *testing.T) {
+	for _, b := range []byte{'\t', '\n', '\r', '\f', ' ', 'a', 0x80} {
+		if isStrayControl(b) {
+			t.Errorf("isStrayControl(%#x) = true, want false", b)
+		}
+	}
+	for _, b := range []byte{0x00, 0x07, 0x08, 0x0b, 0x1b, 0x1f, 0x7f} {
+		if !isStrayControl(b) {
+			t.Errorf("isStrayControl(%#x) = false, want true", b)
+		}
+	}
+}
+
+func TestEscapeSequenceLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected int
+	}{
+		{name: "color", content: "\x1b[31mred", expected: 5},
+		{name: "reset", content: "\x1b[0m", expected: 4},
+		{name: "private mode", content: "\x1b[?25lx", expected: 6},
+		{name: "unterminated control sequence", content: "\x1b[31\nx", expected: 4},
+		{name: "window title ended by BEL", content: "\x1b]0;title\x07x", expected: 10},
+		{name: "hyperlink ended by ST", content: "\x1b]8;;http://x\x1b\\x", expected: 15},
+		{name: "unterminated command", content: "\x1b]0;title\nx", expected: 9},
+		{name: "charset selection", content: "\x1b(Bx", expected: 3},
+		{name: "short escape", content: "\x1bMx", expected: 2},
+		{name: "lone escape", content: "\x1b", expected: 1},
+		{name: "escape before newline", content: "\x1b\n", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := escapeSequenceLength([]byte(tt.content)); result != tt.expected {
+				t.Errorf("escapeSequenceLength(%q) = %d, want %d", tt.content, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewControlCharacterFixer(t *testing.T) {
+	if newControlCharacterFixer("") != nil || newControlCharacterFixer(cli.ControlCharactersReport) != nil {
+		t.Error("newControlCharacterFixer() should only create a fixer for strip")
+	}
+	if newControlCharacterFixer(cli.ControlCharactersStrip) == nil {
+		t.Error("newControlCharacterFixer(strip) = nil")
+	}
+	if newControlCharacterScanner("") != nil || newControlCharacterScanner(cli.ControlCharactersStrip) != nil {
+		t.Error("newControlCharacterScanner() should only create a scanner for report")
+	}
+	if newControlCharacterScanner(cli.ControlCharactersReport) == nil {
+		t.Error("newControlCharacterScanner(report) = nil")
+	}
+}
+
+func TestControlCharacterFixerFix(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		expected     string
+		expectChange string
+	}{
+		{name: "clean", content: "a\tb\r\n\fc\n", expectChange: ""},
+		{name: "colored output", content: "\x1b[32mPASS\x1b[0m ok\n", expected: "PASS ok\n", expectChange: "stripped 2 escape sequence(s) and control character(s)"},
+		{name: "bell and backspace", content: "a\x07b\x08\n", expected: "ab\n", expectChange: "stripped 2 escape sequence(s) and control character(s)"},
+		{name: "title and DEL", content: "\x1b]0;t\x07x\x7f\n", expected: "x\n", expectChange: "stripped 2 escape sequence(s) and control character(s)"},
+		{name: "line endings kept", content: "\x1b[1ma\x1b[m\r\nb\n", expected: "a\r\nb\n", expectChange: "stripped 2 escape sequence(s) and control character(s)"},
+		{name: "UTF-8 kept", content: "café\x1b[0m\n", expected: "café\n", expectChange: "stripped 1 escape sequence(s) and control character(s)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, change := (&controlCharacterFixer{}).fix([]byte(tt.content))
+			if change != tt.expectChange {
+				t.Errorf("fix() change = %q, want %q", change, tt.expectChange)
+			}
+			if tt.expectChange != "" && string(fixed) != tt.expected {
+				t.Errorf("fix() = %q, want %q", fixed, tt.expected)
+			}
+		})
+	}
+}
+
+func TestControlCharacterScannerScan(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{name: "clean", content: "a\tb\n", expected: nil},
+		{
+			name:     "escape sequences",
+			content:  "ok\n\x1b[31mfail\x1b[0m\n",
+			expected: []string{`2:1: ANSI escape sequence "\x1b[31m"`, `2:10: ANSI escape sequence "\x1b[0m"`},
+		},
+		{name: "control character", content: "café\x07\n", expected: []string{"1:5: control character U+0007"}},
+		{name: "lone escape", content: "a\x1b\n", expected: []string{"1:2: control character U+001B"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := (&controlCharacterScanner{}).scan([]byte(tt.content)); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("scan() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAddNewlineIfNeededStripsControlCharacters(t *testing.T) {
+	options := processOptions{
+		policies: newPolicyResolver(cli.PolicySet{
+			Policy: cli.Policy{ControlCharacters: cli.ControlCharactersStrip},
+			Rules:  []cli.Rule{{Pattern: "*.log", Policy: cli.Policy{ControlCharacters: cli.ControlCharactersReport}}},
+		}),
+	}
+	dir := t.TempDir()
+	textFile := filepath.Join(dir, "output.txt")
+	logFile := filepath.Join(dir, "build.log")
+	_ = os.WriteFile(textFile, []byte("\x1b[32mPASS\x1b[0m"), 0o644)
+	_ = os.WriteFile(logFile, []byte("\x1b[32mPASS\x1b[0m\n"), 0o644)
+
+	result := FileResult{Path: textFile}
+	if err := addNewlineIfNeeded(&mockLogger{}, textFile, options, &result); err != nil {
+		t.Fatalf("addNewlineIfNeeded() error = %v", err)
+	}
+	if content, _ := os.ReadFile(textFile); string(content) != "PASS\n" {
+		t.Errorf("File content = %q, want %q", content, "PASS\n")
+	}
+	expectedChanges := []string{"stripped 2 escape sequence(s) and control character(s)", changeAddedNewline}
+	if !reflect.DeepEqual(result.Changes, expectedChanges) {
+		t.Errorf("Changes = %v, want %v", result.Changes, expectedChanges)
+	}
+
+	result = FileResult{Path: logFile}
+	if err := addNewlineIfNeeded(&mockLogger{}, logFile, options, &result); err != nil {
+		t.Fatalf("addNewlineIfNeeded() error = %v", err)
+	}
+	if content, _ := os.ReadFile(logFile); string(content) != "\x1b[32mPASS\x1b[0m\n" || result.Modified() {
+		t.Errorf("Reported file should be untouched, got %q, %v", content, result.Changes)
+	}
+	if len(result.Findings) != 2 {
+		t.Errorf("Findings = %v, want the 2 escape sequences", result.Findings)
+	}
+}
+
+func TestRunReportsControlCharacters(t *testing.T) {
+	policies := cli.PolicySet{Policy: cli.Policy{ControlCharacters: cli.ControlCharactersReport}}
+	filePath := filepath.Join(t.TempDir(), "build.log")
+	_ = os.WriteFile(filePath, []byte("ok\n\x1b[31mfail\n"), 0o644)
+	input := `{"tool_name": "Write", "tool_input": {"file_path": "` + filePath + `"}}`
+
+	logger := &mockLogger{}
+	if code := Run(&cli.Config{Policies: policies}, logger, strings.NewReader(input)); code != exitOK {
+		t.Errorf("Run() = %v, want %v", code, exitOK)
+	}
+	expected := filePath + `:2:1: ANSI escape sequence "\x1b[31m"`
+	if !slices.Contains(logger.infoMessages, expected) {
+		t.Errorf("Info output = %v, want it to contain %q", logger.infoMessages, expected)
+	}
+
+	enforceConfig := &cli.Config{Policies: policies, Enforce: true}
+	report := ProcessFiles(&mockLogger{}, []string{filePath}, newFileFilter(enforceConfig), newProcessOptions(enforceConfig))
+	var buf bytes.Buffer
+	e := newEnforcer()
+	e.Writer = &buf
+	if code := e.enforce(&mockLogger{}, report, nil); code != exitBlocking {
+		t.Errorf("enforce() = %v, want %v", code, exitBlocking)
+	}
+	if !strings.Contains(buf.String(), "- "+expected+"\n") {
+		t.Errorf("Enforce output = %q, want it to list %q", buf.String(), expected)
+	}
+}
@@ -0,0 +1,316 @@
+package processing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/logging"
+	"golang.org/x/text/encoding"
+)
+
+// sniffSize is how much of the start of a file is examined to detect its encoding
+const sniffSize = 4096
+
+// Reasons files in other encodings than UTF-8 are skipped
+const (
+	// skipUndecodable records that a file could not be decoded in its detected or declared encoding
+	skipUndecodable = "not valid %s"
+	// skipUnencodable records that the fixed text could not be encoded back
+	skipUnencodable = "not representable in %s"
+)
+
+// Decoding and encoding errors of wide encodings and legacy charsets
+var (
+	// errPartialCodeUnit is returned when the content ends in the middle of a code unit
+	errPartialCodeUnit = errors.New("content ends with a partial code unit")
+	// errLossyDecoding is returned when the content holds invalid code units, which
+	// could not be written back unchanged
+	errLossyDecoding = errors.New("content holds invalid code units")
+	// errUnencodable is returned when text holds characters the encoding cannot represent
+	errUnencodable = errors.New("content holds characters the encoding cannot represent")
+)
+
+// byteOrder reads and appends code units wider than a byte
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// textEncoding describes how the text of a file is encoded
+type textEncoding struct {
+	// name is shown in debug output
+	name string
+	// unitSize is the size of a code unit in bytes
+	unitSize int
+	// order is the byte order of code units wider than a byte
+	order byteOrder
+	// bom is the byte order mark of the encoding
+	bom []byte
+	// codec converts the text of legacy charsets; nil for UTF encodings
+	codec encoding.Encoding
+}
+
+// Encodings that can be detected
+var (
+	encodingUTF8    = &textEncoding{name: "UTF-8", unitSize: 1, bom: utf8BOM}
+	encodingUTF16LE = &textEncoding{name: "UTF-16LE", unitSize: 2, order: binary.LittleEndian, bom: []byte{0xff, 0xfe}}
+	encodingUTF16BE = &textEncoding{name: "UTF-16BE", unitSize: 2, order: binary.BigEndian, bom: []byte{0xfe, 0xff}}
+	encodingUTF32LE = &textEncoding{name: "UTF-32LE", unitSize: 4, order: binary.LittleEndian, bom: []byte{0xff, 0xfe, 0x00, 0x00}}
+	encodingUTF32BE = &textEncoding{name: "UTF-32BE", unitSize: 4, order: binary.BigEndian, bom: []byte{0x00, 0x00, 0xfe, 0xff}}
+)
+
+// bomEncodings lists the encodings recognized by their byte order mark. UTF-32LE
+// comes before UTF-16LE since its byte order mark starts with that of UTF-16LE.
+var bomEncodings = []*textEncoding{encodingUTF32LE, encodingUTF32BE, encodingUTF8, encodingUTF16LE, encodingUTF16BE}
+
+// isWide reports whether code units are wider than a byte, so that byte-level
+// newline handling would corrupt the file
+func (te *textEncoding) isWide() bool {
+	return te.unitSize > 1
+}
+
+// decode converts content without its byte order mark into a string. Content that
+// would not be encoded back to the same bytes fails to decode.
+func (te *textEncoding) decode(content []byte) (string, error) {
+	var decoded string
+	switch {
+	case te.codec != nil:
+		text, err := te.codec.NewDecoder().Bytes(content)
+		if err != nil {
+			return "", errLossyDecoding
+		}
+		decoded = string(text)
+	case te.isWide():
+		var err error
+		if decoded, err = te.decodeWide(content); err != nil {
+			return "", err
+		}
+	default:
+		return string(content), nil
+	}
+
+	if encoded, err := te.encode(decoded); err != nil || !bytes.Equal(encoded, content) {
+		return "", errLossyDecoding
+	}
+	return decoded, nil
+}
+
+// decodeWide converts content made of UTF-16 or UTF-32 code units into a string
+func (te *textEncoding) decodeWide(content []byte) (string, error) {
+	if len(content)%te.unitSize != 0 {
+		return "", errPartialCodeUnit
+	}
+
+	var text []rune
+	switch te.unitSize {
+	case 2:
+		units := make([]uint16, len(content)/2)
+		for i := range units {
+			units[i] = te.order.Uint16(content[i*2:])
+		}
+		text = utf16.Decode(units)
+	case 4:
+		text = make([]rune, len(content)/4)
+		for i := range text {
+			text[i] = rune(te.order.Uint32(content[i*4:]))
+			if !utf8.ValidRune(text[i]) {
+				return "", errLossyDecoding
+			}
+		}
+	}
+
+	return string(text), nil
+}
+
+// decodeHead decodes the first block of a UTF-16 or UTF-32 file for inspection,
+// skipping its byte order mark and a code unit cut off at the end. Invalid code
+// units decode to U+FFFD.
+func (te *textEncoding) decodeHead(head []byte) []rune {
+	head = bytes.TrimPrefix(head, te.bom)
+	head = head[:len(head)-len(head)%te.unitSize]
+
+	if te.unitSize == 2 {
+		units := make([]uint16, len(head)/2)
+		for i := range units {
+			units[i] = te.order.Uint16(head[i*2:])
+		}
+		return utf16.Decode(units)
+	}
+	text := make([]rune, len(head)/4)
+	for i := range text {
+		text[i] = rune(te.order.Uint32(head[i*4:]))
+		if !utf8.ValidRune(text[i]) {
+			text[i] = utf8.RuneError
+		}
+	}
+	return text
+}
+
+// encode converts text into the encoding, without a byte order mark. It fails when
+// a legacy charset cannot represent the text.
+func (te *textEncoding) encode(text string) ([]byte, error) {
+	switch {
+	case te.codec != nil:
+		encoded, err := te.codec.NewEncoder().Bytes([]byte(text))
+		if err != nil {
+			return nil, errUnencodable
+		}
+		return encoded, nil
+	case !te.isWide():
+		return []byte(text), nil
+	}
+
+	var encoded []byte
+	for _, r := range text {
+		switch te.unitSize {
+		case 2:
+			for _, unit := range utf16.AppendRune(nil, r) {
+				encoded = te.order.AppendUint16(encoded, unit)
+			}
+		case 4:
+			encoded = te.order.AppendUint32(encoded, uint32(r))
+		}
+	}
+	return encoded, nil
+}
+
+// detectEncoding determines the encoding of a file from its first bytes, returning
+// the encoding and whether the file starts with its byte order mark.
+// Files without a byte order mark are taken to be UTF-16 or UTF-32 when their zero
+// bytes fall where those of ASCII text in that encoding would and they decode to
+// printable text, and UTF-8 otherwise.
+func detectEncoding(head []byte) (*textEncoding, bool) {
+	for _, encoding := range bomEncodings {
+		if bytes.HasPrefix(head, encoding.bom) {
+			return encoding, true
+		}
+	}
+	return sniffWideEncoding(head), false
+}
+
+// sniffWideEncoding guesses the encoding of text without a byte order mark from the
+// positions of its zero bytes, which UTF-8 text never contains. Binary data such as
+// arrays of small integers has zero bytes in the same places, so a guess is only
+// kept when the content decodes to printable text.
+func sniffWideEncoding(head []byte) *textEncoding {
+	guess := guessWideEncoding(head)
+	if guess.isWide() && describeUnprintable(guess.decodeHead(head)) != "" {
+		return encodingUTF8
+	}
+	return guess
+}
+
+// guessWideEncoding guesses the encoding from the positions of the zero bytes alone
+func guessWideEncoding(head []byte) *textEncoding {
+	var zeros [4]int
+	quads := len(head) / 4
+	for i := range quads * 4 {
+		if head[i] == 0 {
+			zeros[i%4]++
+		}
+	}
+	if quads == 0 || zeros == [4]int{} {
+		return encodingUTF8
+	}
+
+	// Code points of the Basic Multilingual Plane leave the two high bytes zero in UTF-32
+	switch {
+	case zeros[2] == quads && zeros[3] == quads && zeros[0] < quads:
+		return encodingUTF32LE
+	case zeros[0] == quads && zeros[1] == quads && zeros[3] < quads:
+		return encodingUTF32BE
+	}
+
+	// ASCII characters leave the high byte zero in UTF-16; allow some other characters
+	pairs := quads * 2
+	even, odd := zeros[0]+zeros[2], zeros[1]+zeros[3]
+	switch {
+	case odd*10 >= pairs*9 && even*10 <= pairs:
+		return encodingUTF16LE
+	case even*10 >= pairs*9 && odd*10 <= pairs:
+		return encodingUTF16BE
+	}
+	return encodingUTF8
+}
+
+// readHead reads the first block of a file, which its encoding and kind are detected from
+func readHead(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// fixWideTextIfNeeded applies the policy to a UTF-16 or UTF-32 file by decoding it,
+// fixing the text and encoding it back, so that line endings are handled as code
+// units of the file's encoding. Files that cannot be decoded without loss are
+// skipped. The byte order mark is kept as it is, since the encoding depends on it.
+func fixWideTextIfNeeded(logger logging.Logger, filePath string, encoding *textEncoding, hasBOM bool, policy cli.Policy, options processOptions, result *FileResult) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	var bom []byte
+	if hasBOM {
+		bom = data[:len(encoding.bom)]
+	}
+
+	text, err := encoding.decode(data[len(bom):])
+	if err != nil {
+		logger.Debug(fmt.Sprintf("│ Skipping %s file: %v", encoding.name, err))
+		result.Skipped = fmt.Sprintf(skipUndecodable, encoding.name)
+		return nil
+	}
+	return fixDecodedText(logger, filePath, encoding, bom, text, nil, policy, options, result)
+}
+
+// fixDecodedText applies the policy to the decoded text of a file and writes it back
+// in the encoding after prefix, only when something changed. changes lists what was
+// already changed while decoding. Text the encoding cannot represent is not written.
+func fixDecodedText(logger logging.Logger, filePath string, encoding *textEncoding, prefix []byte, text string, changes []string, policy cli.Policy, options processOptions, result *FileResult) error {
+	policy.BOM = cli.BOMPreserve
+	fixed, fixes := fixTextContent(filePath, policy, text)
+	changes = append(changes, fixes...)
+	scanContent(logger, policy, []byte(fixed), result)
+	if len(changes) == 0 {
+		logger.Debug(fmt.Sprintf("│ %s content already follows the policy", encoding.name))
+		return nil
+	}
+
+	encoded, err := encoding.encode(fixed)
+	if err != nil {
+		logger.Debug(fmt.Sprintf("│ Skipping %s file: %v", encoding.name, err))
+		result.Skipped = fmt.Sprintf(skipUnencodable, encoding.name)
+		return nil
+	}
+
+	if options.dryRun {
+		logger.Debug(fmt.Sprintf("│ %s content needs fixing: %v (dry run, not modified)", encoding.name, changes))
+		result.Changes = append(result.Changes, changes...)
+		return nil
+	}
+
+	logger.Debug(fmt.Sprintf("│ Fixing %s content: %v", encoding.name, changes))
+	if err := os.WriteFile(filePath, append(prefix, encoded...), filePermission); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	result.Changes = append(result.Changes, changes...)
+	logger.Info(fmt.Sprintf("Fixed %s content of %s", encoding.name, filePath))
+	return nil
+}
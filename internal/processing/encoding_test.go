@@ -0,0 +1,176 @@
+package processing
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+// mustEncode encodes test content, panicking when the encoding cannot represent it
+func mustEncode(encoding *textEncoding, text string) []byte {
+	encoded, err := encoding.encode(text)
+	if err != nil {
+		panic(err)
+	}
+	return encoded
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name         string
+		head         []byte
+		expected     *textEncoding
+		expectHasBOM bool
+	}{
+		{name: "empty", head: nil, expected: encodingUTF8},
+		{name: "ASCII", head: []byte("package main\n"), expected: encodingUTF8},
+		{name: "UTF-8 multibyte", head: []byte("こんにちは\n"), expected: encodingUTF8},
+		{name: "UTF-8 BOM", head: []byte("\ufeffhello"), expected: encodingUTF8, expectHasBOM: true},
+		{name: "UTF-16LE BOM", head: []byte{0xff, 0xfe, 'h', 0}, expected: encodingUTF16LE, expectHasBOM: true},
+		{name: "UTF-16BE BOM", head: []byte{0xfe, 0xff, 0, 'h'}, expected: encodingUTF16BE, expectHasBOM: true},
+		{name: "UTF-32LE BOM", head: []byte{0xff, 0xfe, 0, 0, 'h', 0, 0, 0}, expected: encodingUTF32LE, expectHasBOM: true},
+		{name: "UTF-32BE BOM", head: []byte{0, 0, 0xfe, 0xff, 0, 0, 0, 'h'}, expected: encodingUTF32BE, expectHasBOM: true},
+		{name: "UTF-16LE without BOM", head: mustEncode(encodingUTF16LE, "hello world\n"), expected: encodingUTF16LE},
+		{name: "UTF-16BE without BOM", head: mustEncode(encodingUTF16BE, "hello world\n"), expected: encodingUTF16BE},
+		{name: "UTF-32LE without BOM", head: mustEncode(encodingUTF32LE, "hello\n"), expected: encodingUTF32LE},
+		{name: "UTF-32BE without BOM", head: mustEncode(encodingUTF32BE, "hello\n"), expected: encodingUTF32BE},
+		{name: "scattered zero bytes", head: []byte{'a', 0, 0, 'b', 'c', 'd', 0, 'e'}, expected: encodingUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoding, hasBOM := detectEncoding(tt.head)
+			if encoding != tt.expected || hasBOM != tt.expectHasBOM {
+				t.Errorf("detectEncoding() = %s, %v, want %s, %v", encoding.name, hasBOM, tt.expected.name, tt.expectHasBOM)
+			}
+		})
+	}
+}
+
+func TestTextEncodingDecode(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoding  *textEncoding
+		content   []byte
+		expected  string
+		expectErr error
+	}{
+		{name: "UTF-16LE", encoding: encodingUTF16LE, content: []byte{'a', 0, '\n', 0}, expected: "a\n"},
+		{name: "UTF-16BE surrogate pair", encoding: encodingUTF16BE, content: []byte{0xd8, 0x3d, 0xde, 0x00}, expected: "😀"},
+		{name: "UTF-32LE", encoding: encodingUTF32LE, content: []byte{0x42, 0x30, 0, 0}, expected: "あ"},
+		{name: "UTF-16 partial code unit", encoding: encodingUTF16LE, content: []byte{'a', 0, '\n'}, expectErr: errPartialCodeUnit},
+		{name: "UTF-16 unpaired surrogate", encoding: encodingUTF16LE, content: []byte{0x3d, 0xd8, 'a', 0}, expectErr: errLossyDecoding},
+		{name: "UTF-32 out of range", encoding: encodingUTF32LE, content: []byte{0, 0, 0x11, 0}, expectErr: errLossyDecoding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.encoding.decode(tt.content)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("decode() error = %v, want %v", err, tt.expectErr)
+			}
+			if err != nil {
+				return
+			}
+			if result != tt.expected {
+				t.Errorf("decode() = %q, want %q", result, tt.expected)
+			}
+			if encoded, err := tt.encoding.encode(result); err != nil || !bytes.Equal(encoded, tt.content) {
+				t.Errorf("encode() = %v, %v, want %v", encoded, err, tt.content)
+			}
+		})
+	}
+}
+
+func TestAddNewlineIfNeededWideEncodings(t *testing.T) {
+	withBOM := func(encoding *textEncoding, text string) []byte {
+		return append(bytes.Clone(encoding.bom), mustEncode(encoding, text)...)
+	}
+	exactlyOne := newPolicyResolver(cli.PolicySet{Policy: cli.Policy{FinalNewline: cli.FinalNewlineExactlyOne, BOM: cli.BOMStrip}})
+
+	tests := []struct {
+		name          string
+		content       []byte
+		options       processOptions
+		expected      []byte
+		expectChanges []string
+		expectSkipped string
+	}{
+		{
+			name:          "UTF-16LE with BOM",
+			content:       withBOM(encodingUTF16LE, "a\nb"),
+			expected:      withBOM(encodingUTF16LE, "a\nb\n"),
+			expectChanges: []string{changeAddedNewline},
+		},
+		{
+			name:          "UTF-16LE CRLF",
+			content:       withBOM(encodingUTF16LE, "a\r\nb"),
+			expected:      withBOM(encodingUTF16LE, "a\r\nb\r\n"),
+			expectChanges: []string{changeAddedNewline},
+		},
+		{
+			name:          "UTF-16BE without BOM",
+			content:       mustEncode(encodingUTF16BE, "hello\nworld"),
+			expected:      mustEncode(encodingUTF16BE, "hello\nworld\n"),
+			expectChanges: []string{changeAddedNewline},
+		},
+		{
+			name:          "UTF-32LE with BOM",
+			content:       withBOM(encodingUTF32LE, "hello"),
+			expected:      withBOM(encodingUTF32LE, "hello\n"),
+			expectChanges: []string{changeAddedNewline},
+		},
+		{
+			name:     "UTF-16LE already ends with newline",
+			content:  withBOM(encodingUTF16LE, "a\n"),
+			expected: withBOM(encodingUTF16LE, "a\n"),
+		},
+		{
+			name:          "exactly one keeps the wide BOM",
+			content:       withBOM(encodingUTF16LE, "a\n\n\n"),
+			options:       processOptions{policies: exactlyOne},
+			expected:      withBOM(encodingUTF16LE, "a\n"),
+			expectChanges: []string{"removed 2 trailing blank line(s)"},
+		},
+		{
+			name:          "dry run",
+			content:       withBOM(encodingUTF16LE, "a"),
+			options:       processOptions{dryRun: true},
+			expected:      withBOM(encodingUTF16LE, "a"),
+			expectChanges: []string{changeAddedNewline},
+		},
+		{
+			name:          "partial code unit skipped",
+			content:       append(withBOM(encodingUTF16LE, "a"), 'b'),
+			expected:      append(withBOM(encodingUTF16LE, "a"), 'b'),
+			expectSkipped: "not valid UTF-16LE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "file.txt")
+			_ = os.WriteFile(filePath, tt.content, 0o644)
+
+			result := FileResult{Path: filePath}
+			if err := addNewlineIfNeeded(&mockLogger{}, filePath, tt.options, &result); err != nil {
+				t.Fatalf("addNewlineIfNeeded() error = %v", err)
+			}
+			content, _ := os.ReadFile(filePath)
+			if !bytes.Equal(content, tt.expected) {
+				t.Errorf("File content = %v, want %v", content, tt.expected)
+			}
+			if !reflect.DeepEqual(result.Changes, tt.expectChanges) {
+				t.Errorf("Changes = %v, want %v", result.Changes, tt.expectChanges)
+			}
+			if result.Skipped != tt.expectSkipped {
+				t.Errorf("Skipped = %q, want %q", result.Skipped, tt.expectSkipped)
+			}
+		})
+	}
+}
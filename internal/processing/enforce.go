@@ -0,0 +1,74 @@
+package processing
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/koh-sh/ccnewline/internal/logging"
+	"github.com/koh-sh/ccnewline/internal/toolinput"
+)
+
+// enforcer reports files that break the formatting policy instead of fixing them
+type enforcer struct {
+	Writer io.Writer
+}
+
+// newEnforcer creates an enforcer that reports on stderr
+func newEnforcer() *enforcer {
+	return &enforcer{
+		Writer: os.Stderr,
+	}
+}
+
+// enforce explains the violations found by a dry run, changes it would have made
+// and findings it never fixes, and returns the exit code. A Stop hook that already
+// blocked once is let through so Claude cannot loop forever.
+func (e *enforcer) enforce(logger logging.Logger, report *Report, hook *toolinput.HookInput) int {
+	modified, flagged := report.Modified(), report.Flagged()
+	if len(modified) == 0 && len(flagged) == 0 {
+		logger.Debug("No violations found")
+		return exitOK
+	}
+	if hook != nil && hook.StopHookActive {
+		logger.Debug(fmt.Sprintf("Not blocking again: %d file(s) still need fixing", countViolatingFiles(report)))
+		return exitOK
+	}
+
+	fmt.Fprint(e.Writer, describeViolations(modified, flagged))
+	return exitBlocking
+}
+
+// countViolatingFiles counts the files that would be changed or have findings
+func countViolatingFiles(report *Report) int {
+	count := 0
+	for _, result := range report.Results {
+		if result.Modified() || len(result.Findings) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// describeViolations explains to the model which fixes it has to make itself
+func describeViolations(modified, flagged []FileResult) string {
+	var message strings.Builder
+	if len(modified) > 0 {
+		message.WriteString("ccnewline would have made these changes, but enforce mode leaves them to you:\n")
+		for _, result := range modified {
+			message.WriteString("- " + result.describe() + "\n")
+		}
+		message.WriteString("The files were not modified. Edit them so they follow the project's formatting policy.\n")
+	}
+	if len(flagged) > 0 {
+		message.WriteString(findingsHeader + "\n")
+		for _, result := range flagged {
+			for _, finding := range result.locateFindings() {
+				message.WriteString("- " + finding + "\n")
+			}
+		}
+		message.WriteString(findingsFooter + "\n")
+	}
+	return message.String()
+}
@@ -0,0 +1,108 @@
+package processing
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/toolinput"
+)
+
+func TestEnforcerEnforce(t *testing.T) {
+	violation := FileResult{Path: "/p/a.go", Changes: []string{changeAddedNewline}}
+
+	tests := []struct {
+		name         string
+		results      []FileResult
+		hook         *toolinput.HookInput
+		expectCode   int
+		expectOutput string
+	}{
+		{
+			name:       "no violations",
+			results:    []FileResult{{Path: "/p/a.go"}},
+			expectCode: exitOK,
+		},
+		{
+			name:         "violation blocks",
+			results:      []FileResult{violation, {Path: "/p/b.go"}},
+			expectCode:   exitBlocking,
+			expectOutput: "- /p/a.go: added final newline\n",
+		},
+		{
+			name:         "findings block",
+			results:      []FileResult{{Path: "/p/a.go", Findings: []string{"1:6: U+201C left double quotation mark"}}, {Path: "/p/b.go"}},
+			expectCode:   exitBlocking,
+			expectOutput: "- /p/a.go:1:6: U+201C left double quotation mark\n",
+		},
+		{
+			name:       "stop hook already active",
+			results:    []FileResult{violation},
+			hook:       &toolinput.HookInput{HookEventName: "Stop", StopHookActive: true},
+			expectCode: exitOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e := &enforcer{Writer: &buf}
+			code := e.enforce(&mockLogger{}, &Report{Results: tt.results}, tt.hook)
+
+			if code != tt.expectCode {
+				t.Errorf("enforce() = %v, want %v", code, tt.expectCode)
+			}
+			if tt.expectOutput == "" {
+				if buf.Len() != 0 {
+					t.Errorf("Expected no output, got %q", buf.String())
+				}
+				return
+			}
+			if !strings.Contains(buf.String(), tt.expectOutput) {
+				t.Errorf("Output = %q, want it to contain %q", buf.String(), tt.expectOutput)
+			}
+			if strings.Contains(buf.String(), "/p/b.go") {
+				t.Errorf("Output should only list violations, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestRunEnforce(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name       string
+		content    string
+		expectCode int
+	}{
+		{name: "missing newline", content: "content", expectCode: exitBlocking},
+		{name: "has newline", content: "content\n", expectCode: exitOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFile := filepath.Join(tempDir, "test.txt")
+			_ = os.WriteFile(testFile, []byte(tt.content), 0o644)
+
+			// Discard the explanation written to stderr
+			oldStderr := os.Stderr
+			os.Stderr, _ = os.Open(os.DevNull)
+			defer func() { os.Stderr = oldStderr }()
+
+			input := `{"tool_name": "Write", "tool_input": {"file_path": "` + testFile + `"}}`
+			code := Run(&cli.Config{Silent: true, Enforce: true}, &mockLogger{}, strings.NewReader(input))
+
+			if code != tt.expectCode {
+				t.Errorf("Run() = %v, want %v", code, tt.expectCode)
+			}
+			content, _ := os.ReadFile(testFile)
+			if string(content) != tt.content {
+				t.Errorf("File should not be modified in enforce mode, got %q", content)
+			}
+		})
+	}
+}
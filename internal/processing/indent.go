@@ -0,0 +1,322 @@
+package processing
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+// changeConvertedIndentation records that leading indentation was converted
+const changeConvertedIndentation = "converted indentation to %s on %d line(s)"
+
+// defaultIndentSize is the width of a tab when the policy does not set indent_size
+const defaultIndentSize = 4
+
+// makefileNames are the file names of Makefiles, whose recipe lines must start with a tab
+var makefileNames = []string{"makefile", "gnumakefile"}
+
+// literalTracker follows the multi-line string literals of a language line by line,
+// so that the indentation of lines inside them, which is part of the string, is kept
+type literalTracker interface {
+	// next reports whether the line starts inside a literal and advances past it
+	next(line string) bool
+}
+
+// newLiteralTracker returns the literal tracker for the file's language,
+// or nil when its literals are not tracked
+func newLiteralTracker(filePath string) literalTracker {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".go":
+		return &goRawStringTracker{}
+	case ".py", ".pyi":
+		return &pythonStringTracker{}
+	case ".sh", ".bash", ".zsh", ".ksh":
+		return &heredocTracker{pattern: shellHeredocPattern}
+	case ".rb":
+		return &heredocTracker{pattern: rubyHeredocPattern}
+	}
+	return nil
+}
+
+// isMakefile reports whether the file is a Makefile
+func isMakefile(filePath string) bool {
+	name := strings.ToLower(filepath.Base(filePath))
+	return slices.Contains(makefileNames, name) || strings.HasSuffix(name, ".mk")
+}
+
+// indentFixer converts the leading indentation of lines to tabs or spaces
+type indentFixer struct {
+	// useTabs indents with tabs, keeping spaces that align within a tab width
+	useTabs bool
+	// size is the number of columns of an indentation level and of a tab
+	size int
+	// recipes follows the rules of a Makefile, so that only recipe lines start with a
+	// tab after conversion; nil for other files
+	recipes *recipeTracker
+	// tracker follows the file's string literals; nil when they are not tracked
+	tracker literalTracker
+}
+
+// newIndentFixer creates an indentation fixer for the policy, or returns nil
+// when the policy leaves indentation alone
+func newIndentFixer(filePath string, policy cli.Policy) *indentFixer {
+	if policy.IndentStyle == "" {
+		return nil
+	}
+	size := policy.IndentSize
+	if size == 0 {
+		size = defaultIndentSize
+	}
+	return &indentFixer{
+		useTabs: policy.IndentStyle == cli.IndentStyleTab,
+		size:    size,
+		recipes: newRecipeTracker(filePath),
+		tracker: newLiteralTracker(filePath),
+	}
+}
+
+// fix converts the indentation of each line outside string literals
+func (inf *indentFixer) fix(content []byte) ([]byte, string) {
+	var fixed bytes.Buffer
+	converted := 0
+
+	for len(content) > 0 {
+		end := bytes.IndexByte(content, newlineByte)
+		line, rest := content, []byte(nil)
+		if end >= 0 {
+			line, rest = content[:end+1], content[end+1:]
+		}
+		content = rest
+
+		inLiteral := inf.tracker != nil && inf.tracker.next(string(line))
+		if inLiteral || inf.keepsMakefileLine(line) {
+			fixed.Write(line)
+			continue
+		}
+
+		body := bytes.TrimLeft(line, " \t")
+		if len(bytes.TrimRight(body, "\r\n")) == 0 {
+			// Whitespace-only lines are left to trim_trailing_whitespace
+			fixed.Write(line)
+			continue
+		}
+		indent := inf.convert(line[:len(line)-len(body)])
+		if !bytes.Equal(indent, line[:len(line)-len(body)]) {
+			converted++
+		}
+		fixed.Write(indent)
+		fixed.Write(body)
+	}
+
+	if converted == 0 {
+		return nil, ""
+	}
+	style := "spaces"
+	if inf.useTabs {
+		style = "tabs"
+	}
+	return fixed.Bytes(), fmt.Sprintf(changeConvertedIndentation, style, converted)
+}
+
+// keepsMakefileLine reports whether a Makefile line must be left alone: under space,
+// recipe lines keep their tab, and under tab, indented lines outside recipes, such as
+// variables in conditionals, must not become recipes
+func (inf *indentFixer) keepsMakefileLine(line []byte) bool {
+	if inf.recipes == nil {
+		return false
+	}
+	recipe := inf.recipes.next(string(line))
+	if inf.useTabs {
+		return !recipe
+	}
+	return bytes.HasPrefix(line, []byte("\t"))
+}
+
+// convert returns indentation of the same width in the configured style
+func (inf *indentFixer) convert(indent []byte) []byte {
+	width := 0
+	for _, b := range indent {
+		if b == '\t' {
+			width += inf.size - width%inf.size
+		} else {
+			width++
+		}
+	}
+	if !inf.useTabs {
+		return bytes.Repeat([]byte(" "), width)
+	}
+	converted := bytes.Repeat([]byte("\t"), width/inf.size)
+	return append(converted, bytes.Repeat([]byte(" "), width%inf.size)...)
+}
+
+// makefileDirectives are the Makefile directives, which are never rule lines even
+// when they hold a colon
+var makefileDirectives = []string{
+	"ifeq", "ifneq", "ifdef", "ifndef", "else", "endif", "define", "endef",
+	"include", "-include", "sinclude", "export", "unexport", "override", "vpath",
+}
+
+// recipeTracker follows the rules of a Makefile line by line to tell recipe lines,
+// which follow a rule line or another recipe line, from other indented lines
+type recipeTracker struct {
+	// inRule is set after a rule line, until a line that is not indented ends its recipe
+	inRule bool
+}
+
+// newRecipeTracker creates a recipe tracker for Makefiles, or returns nil for other files
+func newRecipeTracker(filePath string) *recipeTracker {
+	if !isMakefile(filePath) {
+		return nil
+	}
+	return &recipeTracker{}
+}
+
+// next reports whether the line is a recipe line, indented with a tab or spaces,
+// and advances past it. Blank lines and comments do not end a recipe.
+func (rt *recipeTracker) next(line string) bool {
+	body := strings.TrimRight(line, "\r\n")
+	switch {
+	case strings.TrimSpace(body) == "" || strings.HasPrefix(body, "#"):
+		return false
+	case body[0] == '\t' || body[0] == ' ':
+		return rt.inRule
+	}
+	rt.inRule = isRuleLine(body)
+	return false
+}
+
+// isRuleLine reports whether a line that is not indented starts a rule, such as
+// "build: deps", rather than setting a variable or holding a directive
+func isRuleLine(line string) bool {
+	if fields := strings.Fields(line); slices.Contains(makefileDirectives, fields[0]) {
+		return false
+	}
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 || strings.HasPrefix(line[colon:], ":=") || strings.HasPrefix(line[colon:], "::=") {
+		return false
+	}
+	// An assignment whose value holds a colon, such as "OBJ = $(SRC:.c=.o)"
+	equals := strings.IndexByte(line, '=')
+	return equals < 0 || equals > colon
+}
+
+// goRawStringTracker follows Go raw string literals, which are enclosed in backquotes
+type goRawStringTracker struct {
+	inRaw bool
+}
+
+// next scans the line, skipping interpreted strings, rune literals and line comments
+func (grt *goRawStringTracker) next(line string) bool {
+	started := grt.inRaw
+	for i := 0; i < len(line); i++ {
+		if grt.inRaw {
+			if line[i] == '`' {
+				grt.inRaw = false
+			}
+			continue
+		}
+		switch line[i] {
+		case '`':
+			grt.inRaw = true
+		case '"', '\'':
+			i = skipQuoted(line, i)
+		case '/':
+			if strings.HasPrefix(line[i:], "//") {
+				return started
+			}
+		}
+	}
+	return started
+}
+
+// pythonStringTracker follows Python triple-quoted strings
+type pythonStringTracker struct {
+	// delimiter is the quote sequence of the open string, empty outside strings
+	delimiter string
+}
+
+// next scans the line, skipping single-quoted strings and comments
+func (pst *pythonStringTracker) next(line string) bool {
+	started := pst.delimiter != ""
+	for i := 0; i < len(line); i++ {
+		if pst.delimiter != "" {
+			if line[i] == '\\' {
+				i++
+			} else if strings.HasPrefix(line[i:], pst.delimiter) {
+				i += len(pst.delimiter) - 1
+				pst.delimiter = ""
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line[i:], `"""`), strings.HasPrefix(line[i:], `'''`):
+			pst.delimiter = line[i : i+3]
+			i += 2
+		case line[i] == '"' || line[i] == '\'':
+			i = skipQuoted(line, i)
+		case line[i] == '#':
+			return started
+		}
+	}
+	return started
+}
+
+// Patterns matching the start of a heredoc, capturing its modifier and delimiter
+var (
+	// shellHeredocPattern matches shell heredocs; here-strings (<<<) and arithmetic
+	// shifts by a number are not heredocs
+	shellHeredocPattern = regexp.MustCompile(`(?:^|[^<])<<(-?)[ \t]*(?:'([A-Za-z_]\w*)'|"([A-Za-z_]\w*)"|([A-Za-z_]\w*))`)
+	// rubyHeredocPattern matches Ruby heredocs, whose delimiter follows << directly
+	// and is upper case, unlike the operand of the append operator
+	rubyHeredocPattern = regexp.MustCompile(`(?:^|[^<])<<([-~]?)(?:'([A-Z_]\w*)'|"([A-Z_]\w*)"|([A-Z_][A-Z0-9_]*))\b`)
+)
+
+// heredocTracker follows shell and Ruby heredocs
+type heredocTracker struct {
+	// pattern matches the start of a heredoc in the file's language
+	pattern *regexp.Regexp
+	// delimiter ends the open heredoc, empty outside heredocs
+	delimiter string
+	// indented allows the closing delimiter to be indented (<<- and <<~)
+	indented bool
+}
+
+// next reports whether the line is part of a heredoc body or its closing delimiter
+func (ht *heredocTracker) next(line string) bool {
+	if ht.delimiter != "" {
+		closing := strings.TrimRight(line, "\r\n")
+		if ht.indented {
+			closing = strings.TrimLeft(closing, " \t")
+		}
+		if closing == ht.delimiter {
+			ht.delimiter = ""
+		}
+		return true
+	}
+
+	if match := ht.pattern.FindStringSubmatch(line); match != nil {
+		ht.delimiter = match[2] + match[3] + match[4]
+		ht.indented = match[1] != ""
+	}
+	return false
+}
+
+// skipQuoted returns the index of the quote closing the string that starts at start,
+// honoring backslash escapes, or the end of the line when the string is not closed
+func skipQuoted(line string, start int) int {
+	quote := line[start]
+	for i := start + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+	return len(line)
+}
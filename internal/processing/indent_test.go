@@ -0,0 +1,277 @@
+package processing
+
+import (
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+func TestIsMakefile(t *testing.T) {
+	tests := []struct {
+		filePath string
+		expected bool
+	}{
+		{filePath: "/a/Makefile", expected: true},
+		{filePath: "/a/GNUmakefile", expected: true},
+		{filePath: "/a/rules.mk", expected: true},
+		{filePath: "/a/main.go", expected: false},
+		{filePath: "/a/Makefile.md", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			if result := isMakefile(tt.filePath); result != tt.expected {
+				t.Errorf("isMakefile(%q) = %v, want %v", tt.filePath, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsRuleLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected bool
+	}{
+		{line: "build:", expected: true},
+		{line: "all: build test", expected: true},
+		{line: "%.o: %.c", expected: true},
+		{line: "clean:: tidy", expected: true},
+		{line: "FOO := bar", expected: false},
+		{line: "FOO ::= bar", expected: false},
+		{line: "OBJ = $(SRC:.c=.o)", expected: false},
+		{line: "ifeq ($(A),a:b)", expected: false},
+		{line: "FOO = bar", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if result := isRuleLine(tt.line); result != tt.expected {
+				t.Errorf("isRuleLine(%q) = %v, want %v", tt.line, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewIndentFixer(t *testing.T) {
+	if fixer := newIndentFixer("main.go", cli.Policy{}); fixer != nil {
+		t.Errorf("newIndentFixer() without indent_style = %v, want nil", fixer)
+	}
+
+	fixer := newIndentFixer("Makefile", cli.Policy{IndentStyle: cli.IndentStyleSpace})
+	if fixer == nil || fixer.useTabs || fixer.size != defaultIndentSize || fixer.recipes == nil {
+		t.Errorf("newIndentFixer() for a Makefile = %+v", fixer)
+	}
+
+	fixer = newIndentFixer("main.go", cli.Policy{IndentStyle: cli.IndentStyleTab, IndentSize: 8})
+	if fixer == nil || !fixer.useTabs || fixer.size != 8 || fixer.recipes != nil || fixer.tracker == nil {
+		t.Errorf("newIndentFixer() for a Go file = %+v", fixer)
+	}
+}
+
+func TestIndentFixerFix(t *testing.T) {
+	tests := []struct {
+		name         string
+		filePath     string
+		policy       cli.Policy
+		content      string
+		expected     string
+		expectChange string
+	}{
+		{
+			name:         "spaces to tabs",
+			filePath:     "main.go",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleTab, IndentSize: 4},
+			content:      "func f() {\n    x := 1\n        y := 2\n\tz := 3\n}\n",
+			expected:     "func f() {\n\tx := 1\n\t\ty := 2\n\tz := 3\n}\n",
+			expectChange: "converted indentation to tabs on 2 line(s)",
+		},
+		{
+			name:         "tabs to spaces",
+			filePath:     "config.yaml",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleSpace, IndentSize: 2},
+			content:      "a:\n\tb: 1\n\t\tc: 2\n",
+			expected:     "a:\n  b: 1\n    c: 2\n",
+			expectChange: "converted indentation to spaces on 2 line(s)",
+		},
+		{
+			name:         "alignment spaces kept after tabs",
+			filePath:     "main.go",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleTab, IndentSize: 4},
+			content:      "      x\n",
+			expected:     "\t  x\n",
+			expectChange: "converted indentation to tabs on 1 line(s)",
+		},
+		{
+			name:         "CRLF kept",
+			filePath:     "a.txt",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleSpace, IndentSize: 4},
+			content:      "a\r\n\tb\r\n",
+			expected:     "a\r\n    b\r\n",
+			expectChange: "converted indentation to spaces on 1 line(s)",
+		},
+		{
+			name:     "whitespace-only lines left alone",
+			filePath: "a.txt",
+			policy:   cli.Policy{IndentStyle: cli.IndentStyleSpace, IndentSize: 4},
+			content:  "a\n\t\nb\n",
+		},
+		{
+			name:         "Makefile recipes keep tabs",
+			filePath:     "Makefile",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleSpace, IndentSize: 4},
+			content:      "build:\n\tgo build ./...\nVAR = a \\\n\tb\n",
+			expectChange: "",
+		},
+		{
+			name:         "Makefile recipes indented with spaces get tabs",
+			filePath:     "Makefile",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleTab, IndentSize: 4},
+			content:      "build:\n    go build ./...\n\n    go vet ./...\n",
+			expected:     "build:\n\tgo build ./...\n\n\tgo vet ./...\n",
+			expectChange: "converted indentation to tabs on 2 line(s)",
+		},
+		{
+			name:         "Makefile lines indented with spaces do not become recipes",
+			filePath:     "Makefile",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleTab, IndentSize: 4},
+			content:      "ifeq ($(OS),Linux)\n    FOO = bar\nendif\nbuild:\n\techo $(FOO)\n",
+			expectChange: "",
+		},
+		{
+			name:         "Makefile recipe continuation gets tabs",
+			filePath:     "rules.mk",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleTab, IndentSize: 4},
+			content:      "build:\n\tgo build \\\n\t    ./...\nX = a \\\n    b\n",
+			expected:     "build:\n\tgo build \\\n\t\t./...\nX = a \\\n    b\n",
+			expectChange: "converted indentation to tabs on 1 line(s)",
+		},
+		{
+			name:         "Go raw string left alone",
+			filePath:     "main.go",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleTab, IndentSize: 4},
+			content:      "var s = `\n    kept\n    also kept`\n    x := \"`\"\n    y := 1\n",
+			expected:     "var s = `\n    kept\n    also kept`\n\tx := \"`\"\n\ty := 1\n",
+			expectChange: "converted indentation to tabs on 2 line(s)",
+		},
+		{
+			name:         "Python triple-quoted string left alone",
+			filePath:     "app.py",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleSpace, IndentSize: 4},
+			content:      "def f():\n\tdoc = \"\"\"\n\tkept\n\t\"\"\"\n\treturn doc\n",
+			expected:     "def f():\n    doc = \"\"\"\n\tkept\n\t\"\"\"\n    return doc\n",
+			expectChange: "converted indentation to spaces on 2 line(s)",
+		},
+		{
+			name:         "shell heredoc left alone",
+			filePath:     "run.sh",
+			policy:       cli.Policy{IndentStyle: cli.IndentStyleSpace, IndentSize: 2},
+			content:      "if true; then\n\tcat <<-'EOF'\n\t\tkept\n\tEOF\n\techo done\nfi\n",
+			expected:     "if true; then\n  cat <<-'EOF'\n\t\tkept\n\tEOF\n  echo done\nfi\n",
+			expectChange: "converted indentation to spaces on 2 line(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, change := newIndentFixer(tt.filePath, tt.policy).fix([]byte(tt.content))
+			if change != tt.expectChange {
+				t.Errorf("fix() change = %q, want %q", change, tt.expectChange)
+			}
+			if change != "" && string(fixed) != tt.expected {
+				t.Errorf("fix() = %q, want %q", fixed, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLiteralTrackers(t *testing.T) {
+	tests := []struct {
+		name     string
+		tracker  literalTracker
+		lines    []string
+		expected []bool
+	}{
+		{
+			name:     "Go backquote in comment",
+			tracker:  &goRawStringTracker{},
+			lines:    []string{"x := 1 // a ` here", "y := 2"},
+			expected: []bool{false, false},
+		},
+		{
+			name:     "Go backquote in rune literal",
+			tracker:  &goRawStringTracker{},
+			lines:    []string{"c := '`'", "y := 2"},
+			expected: []bool{false, false},
+		},
+		{
+			name:     "Go raw string across lines",
+			tracker:  &goRawStringTracker{},
+			lines:    []string{"s := `a", "b", "c`", "d"},
+			expected: []bool{false, true, true, false},
+		},
+		{
+			name:     "Python single-line triple quotes",
+			tracker:  &pythonStringTracker{},
+			lines:    []string{`x = """one line"""`, "y = 1"},
+			expected: []bool{false, false},
+		},
+		{
+			name:     "Python quotes in comment",
+			tracker:  &pythonStringTracker{},
+			lines:    []string{`x = 1  # """`, "y = 1"},
+			expected: []bool{false, false},
+		},
+		{
+			name:     "Python single-quoted triple string",
+			tracker:  &pythonStringTracker{},
+			lines:    []string{"x = '''", "a", "'''", "b"},
+			expected: []bool{false, true, true, false},
+		},
+		{
+			name:     "heredoc with unindented delimiter",
+			tracker:  &heredocTracker{pattern: shellHeredocPattern},
+			lines:    []string{"cat <<EOF > out", "  a", "  EOF", "EOF", "b"},
+			expected: []bool{false, true, true, true, false},
+		},
+		{
+			name:     "Ruby squiggly heredoc",
+			tracker:  &heredocTracker{pattern: rubyHeredocPattern},
+			lines:    []string{"text = <<~SQL", "  SELECT 1", "  SQL", "x"},
+			expected: []bool{false, true, true, false},
+		},
+		{
+			name:     "here-string is not a heredoc",
+			tracker:  &heredocTracker{pattern: shellHeredocPattern},
+			lines:    []string{"grep x <<< word", "  a"},
+			expected: []bool{false, false},
+		},
+		{
+			name:     "shell arithmetic shift is not a heredoc",
+			tracker:  &heredocTracker{pattern: shellHeredocPattern},
+			lines:    []string{"x=$((1 << 2))", "  a"},
+			expected: []bool{false, false},
+		},
+		{
+			name:     "shell heredoc after a space",
+			tracker:  &heredocTracker{pattern: shellHeredocPattern},
+			lines:    []string{"cat << \"END\"", "  a", "END", "b"},
+			expected: []bool{false, true, true, false},
+		},
+		{
+			name:     "Ruby append is not a heredoc",
+			tracker:  &heredocTracker{pattern: rubyHeredocPattern},
+			lines:    []string{"items << item", "items <<VALUE_LIST", "  a"},
+			expected: []bool{false, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, line := range tt.lines {
+				if result := tt.tracker.next(line + "\n"); result != tt.expected[i] {
+					t.Errorf("next(%q) = %v, want %v", line, result, tt.expected[i])
+				}
+			}
+		})
+	}
+}
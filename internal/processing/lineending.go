@@ -0,0 +1,181 @@
+package processing
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+// Line endings a final newline can be written with
+const (
+	lineEndingLF   = "\n"
+	lineEndingCRLF = "\r\n"
+	lineEndingCR   = "\r"
+)
+
+// carriageReturnByte represents the byte value of a carriage return character (\r)
+const carriageReturnByte = 0x0d
+
+// changeNormalizedLineEndings records that line endings were converted
+const changeNormalizedLineEndings = "normalized %d line ending(s) to %s"
+
+// lineEndingNames maps line endings to the names used in change descriptions
+var lineEndingNames = map[string]string{
+	lineEndingLF:   "LF",
+	lineEndingCRLF: "CRLF",
+	lineEndingCR:   "CR",
+}
+
+// lineEndingCounter counts the line endings of content written to it
+type lineEndingCounter struct {
+	lf, crlf, cr int
+	// pendingCR is set when the last byte seen was a carriage return,
+	// which may be the start of a CRLF split across writes
+	pendingCR bool
+	// last is the last byte seen
+	last byte
+}
+
+// Write counts the line endings in the next chunk of content
+func (lec *lineEndingCounter) Write(chunk []byte) (int, error) {
+	for _, b := range chunk {
+		switch {
+		case b == newlineByte && lec.pendingCR:
+			lec.crlf++
+		case b == newlineByte:
+			lec.lf++
+		case lec.pendingCR:
+			// The previous carriage return was not followed by a line feed
+			lec.cr++
+		}
+		lec.pendingCR = b == carriageReturnByte
+		lec.last = b
+	}
+	return len(chunk), nil
+}
+
+// dominant returns the most frequent line ending, preferring LF on ties and
+// for content without line breaks. A trailing carriage return counts as a bare CR.
+func (lec *lineEndingCounter) dominant() string {
+	cr := lec.cr
+	if lec.pendingCR {
+		cr++
+	}
+	switch {
+	case lec.crlf > lec.lf && lec.crlf >= cr:
+		return lineEndingCRLF
+	case cr > lec.lf && cr > lec.crlf:
+		return lineEndingCR
+	default:
+		return lineEndingLF
+	}
+}
+
+// missingEnding returns what must be appended to content ending in lastByte so that
+// it ends with a line break in the dominant style, or "" when it already does.
+// A trailing bare CR completes a CRLF in CRLF files and is a line break of its own otherwise.
+func missingEnding(lastByte byte, dominant string) string {
+	switch lastByte {
+	case newlineByte:
+		return ""
+	case carriageReturnByte:
+		if dominant == lineEndingCRLF {
+			return lineEndingLF
+		}
+		return ""
+	default:
+		return dominant
+	}
+}
+
+// missing returns what the content seen so far lacks to end with a line break
+func (lec *lineEndingCounter) missing() string {
+	return missingEnding(lec.last, lec.dominant())
+}
+
+// missingEndingFromContent returns what content lacks to end with a line break
+// in its dominant style, or "" when nothing is missing
+func missingEndingFromContent(content []byte) string {
+	if len(content) == 0 {
+		return lineEndingLF
+	}
+	var counter lineEndingCounter
+	_, _ = counter.Write(content)
+	return counter.missing()
+}
+
+// missingEndingFromFile returns what a file lacks to end with a line break
+// in its dominant style, or "" when nothing is missing
+func missingEndingFromFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var counter lineEndingCounter
+	if _, err := io.Copy(&counter, file); err != nil {
+		return "", err
+	}
+	return counter.missing(), nil
+}
+
+// lineEndingFixer converts every line ending of the content to a single style
+type lineEndingFixer struct {
+	// target is the line ending to convert to; empty converts to the dominant one
+	target string
+}
+
+// newLineEndingFixer creates a line ending fixer for the policy, or returns nil
+// when the policy leaves line endings alone
+func newLineEndingFixer(policy string) *lineEndingFixer {
+	switch policy {
+	case cli.EndOfLineLF:
+		return &lineEndingFixer{target: lineEndingLF}
+	case cli.EndOfLineCRLF:
+		return &lineEndingFixer{target: lineEndingCRLF}
+	case cli.EndOfLinePreserveDominant:
+		return &lineEndingFixer{}
+	}
+	return nil
+}
+
+// fix rewrites the line endings that differ from the target
+func (lef *lineEndingFixer) fix(content []byte) ([]byte, string) {
+	target := lef.target
+	if target == "" {
+		var counter lineEndingCounter
+		_, _ = counter.Write(content)
+		target = counter.dominant()
+	}
+
+	var fixed bytes.Buffer
+	converted := 0
+	for i := 0; i < len(content); i++ {
+		ending := ""
+		switch {
+		case content[i] == carriageReturnByte && i+1 < len(content) && content[i+1] == newlineByte:
+			ending = lineEndingCRLF
+			i++
+		case content[i] == carriageReturnByte:
+			ending = lineEndingCR
+		case content[i] == newlineByte:
+			ending = lineEndingLF
+		default:
+			fixed.WriteByte(content[i])
+			continue
+		}
+		if ending != target {
+			converted++
+		}
+		fixed.WriteString(target)
+	}
+
+	if converted == 0 {
+		return nil, ""
+	}
+	return fixed.Bytes(), fmt.Sprintf(changeNormalizedLineEndings, converted, lineEndingNames[target])
+}
@@ -0,0 +1,172 @@
+package processing
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+func TestLineEndingCounterDominant(t *testing.T) {
+	tests := []struct {
+		name     string
+		chunks   []string
+		expected string
+	}{
+		{name: "no line breaks", chunks: []string{"hello"}, expected: lineEndingLF},
+		{name: "LF", chunks: []string{"a\nb\n"}, expected: lineEndingLF},
+		{name: "CRLF", chunks: []string{"a\r\nb\r\n"}, expected: lineEndingCRLF},
+		{name: "CR", chunks: []string{"a\rb\r"}, expected: lineEndingCR},
+		{name: "mostly CRLF", chunks: []string{"a\r\nb\r\nc\n"}, expected: lineEndingCRLF},
+		{name: "tie prefers LF", chunks: []string{"a\r\nb\n"}, expected: lineEndingLF},
+		{name: "CRLF split across writes", chunks: []string{"a\r", "\nb\r", "\nc"}, expected: lineEndingCRLF},
+		{name: "CRLF with trailing CR", chunks: []string{"a\r\nb\r\nc\r"}, expected: lineEndingCRLF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var counter lineEndingCounter
+			for _, chunk := range tt.chunks {
+				_, _ = counter.Write([]byte(chunk))
+			}
+			if result := counter.dominant(); result != tt.expected {
+				t.Errorf("dominant() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMissingEndingFromContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "ends with LF", content: "a\nb\n", expected: ""},
+		{name: "LF file without final newline", content: "a\nb", expected: "\n"},
+		{name: "single line", content: "hello", expected: "\n"},
+		{name: "ends with CRLF", content: "a\r\nb\r\n", expected: ""},
+		{name: "CRLF file without final newline", content: "a\r\nb", expected: "\r\n"},
+		{name: "CRLF file ending in bare CR", content: "a\r\nb\r", expected: "\n"},
+		{name: "CR file", content: "a\rb\r", expected: ""},
+		{name: "LF file ending in bare CR", content: "a\nb\nc\r", expected: ""},
+		{name: "empty", content: "", expected: "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := missingEndingFromContent([]byte(tt.content)); result != tt.expected {
+				t.Errorf("missingEndingFromContent(%q) = %q, want %q", tt.content, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMissingEndingFromFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "windows.txt")
+	_ = os.WriteFile(filePath, []byte("line1\r\nline2\r\nline3"), 0o644)
+
+	ending, err := missingEndingFromFile(filePath)
+	if err != nil {
+		t.Fatalf("missingEndingFromFile() error = %v", err)
+	}
+	if ending != "\r\n" {
+		t.Errorf("missingEndingFromFile() = %q, want %q", ending, "\r\n")
+	}
+
+	if _, err := missingEndingFromFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Expected error for non-existent file")
+	}
+}
+
+func TestProcessSingleFileLineEndings(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name          string
+		content       string
+		expected      string
+		expectChanged bool
+	}{
+		{name: "LF file", content: "a\nb", expected: "a\nb\n", expectChanged: true},
+		{name: "CRLF file", content: "a\r\nb", expected: "a\r\nb\r\n", expectChanged: true},
+		{name: "CRLF file ending in bare CR", content: "a\r\nb\r", expected: "a\r\nb\r\n", expectChanged: true},
+		{name: "CR file", content: "a\rb\r", expected: "a\rb\r", expectChanged: false},
+		{name: "CRLF file with final newline", content: "a\r\nb\r\n", expected: "a\r\nb\r\n", expectChanged: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(tempDir, tt.name+".txt")
+			_ = os.WriteFile(filePath, []byte(tt.content), 0o644)
+
+			result, err := processSingleFile(&mockLogger{}, filePath, processOptions{})
+			if err != nil {
+				t.Fatalf("processSingleFile() error = %v", err)
+			}
+			if result.Modified() != tt.expectChanged {
+				t.Errorf("Modified() = %v, want %v", result.Modified(), tt.expectChanged)
+			}
+			content, _ := os.ReadFile(filePath)
+			if string(content) != tt.expected {
+				t.Errorf("File content = %q, want %q", content, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLineEndingFixerFix(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		content      string
+		expected     string
+		expectChange string
+	}{
+		{name: "LF to CRLF", policy: cli.EndOfLineCRLF, content: "a\nb\n", expected: "a\r\nb\r\n", expectChange: "normalized 2 line ending(s) to CRLF"},
+		{name: "mixed to LF", policy: cli.EndOfLineLF, content: "a\r\nb\nc\r", expected: "a\nb\nc\n", expectChange: "normalized 2 line ending(s) to LF"},
+		{name: "already LF", policy: cli.EndOfLineLF, content: "a\nb\n", expectChange: ""},
+		{name: "dominant CRLF", policy: cli.EndOfLinePreserveDominant, content: "a\r\nb\r\nc\nd\r\n", expected: "a\r\nb\r\nc\r\nd\r\n", expectChange: "normalized 1 line ending(s) to CRLF"},
+		{name: "dominant LF", policy: cli.EndOfLinePreserveDominant, content: "a\nb\nc\r\n", expected: "a\nb\nc\n", expectChange: "normalized 1 line ending(s) to LF"},
+		{name: "no line endings", policy: cli.EndOfLineCRLF, content: "abc", expectChange: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, change := newLineEndingFixer(tt.policy).fix([]byte(tt.content))
+			if change != tt.expectChange {
+				t.Errorf("fix() change = %q, want %q", change, tt.expectChange)
+			}
+			if change != "" && string(fixed) != tt.expected {
+				t.Errorf("fix() = %q, want %q", fixed, tt.expected)
+			}
+		})
+	}
+
+	if fixer := newLineEndingFixer(""); fixer != nil {
+		t.Errorf("newLineEndingFixer(\"\") = %v, want nil", fixer)
+	}
+}
+
+func TestAddNewlineIfNeededEndOfLine(t *testing.T) {
+	options := processOptions{
+		policies: newPolicyResolver(cli.PolicySet{Policy: cli.Policy{EndOfLine: cli.EndOfLineCRLF}}),
+	}
+	filePath := filepath.Join(t.TempDir(), "file.txt")
+	_ = os.WriteFile(filePath, []byte("a\r\nb\nc"), 0o644)
+
+	result := FileResult{Path: filePath}
+	if err := addNewlineIfNeeded(&mockLogger{}, filePath, options, &result); err != nil {
+		t.Fatalf("addNewlineIfNeeded() error = %v", err)
+	}
+	content, _ := os.ReadFile(filePath)
+	if string(content) != "a\r\nb\r\nc\r\n" {
+		t.Errorf("File content = %q, want %q", content, "a\r\nb\r\nc\r\n")
+	}
+	expectedChanges := []string{"normalized 1 line ending(s) to CRLF", changeAddedNewline}
+	if !reflect.DeepEqual(result.Changes, expectedChanges) {
+		t.Errorf("Changes = %v, want %v", result.Changes, expectedChanges)
+	}
+}
@@ -0,0 +1,379 @@
+package processing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/koh-sh/ccnewline/internal/logging"
+)
+
+// notebookExtension is the file extension of Jupyter notebooks
+const notebookExtension = ".ipynb"
+
+// changeNormalizedCells records that notebook cell sources were normalized
+const changeNormalizedCells = "normalized trailing newlines in %d notebook cell(s)"
+
+// errInvalidNotebook is returned when a notebook is not a JSON object
+var errInvalidNotebook = errors.New("notebook is not a JSON object")
+
+// isNotebook checks if the file is a Jupyter notebook
+func isNotebook(filePath string) bool {
+	return strings.EqualFold(filepath.Ext(filePath), notebookExtension)
+}
+
+// jsonNode is an order-preserving JSON value. Scalars keep their raw encoding
+// so that re-serializing an unchanged value reproduces it byte for byte.
+type jsonNode struct {
+	// kind is '{' for objects, '[' for arrays and 0 for scalars
+	kind byte
+	// raw is the encoded value of a scalar
+	raw []byte
+	// keys holds the decoded object keys, rawKeys their original encoding
+	keys    []string
+	rawKeys [][]byte
+	// values holds object member values or array elements
+	values []*jsonNode
+}
+
+// get returns the value of an object member, or nil if absent
+func (n *jsonNode) get(key string) *jsonNode {
+	if n.kind != '{' {
+		return nil
+	}
+	if i := slices.Index(n.keys, key); i >= 0 {
+		return n.values[i]
+	}
+	return nil
+}
+
+// set replaces the value of an existing object member
+func (n *jsonNode) set(key string, value *jsonNode) {
+	if i := slices.Index(n.keys, key); i >= 0 {
+		n.values[i] = value
+	}
+}
+
+// writeCompact writes the node as compact JSON
+func (n *jsonNode) writeCompact(buf *bytes.Buffer) {
+	switch n.kind {
+	case '{':
+		buf.WriteByte('{')
+		for i := range n.values {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(n.rawKeys[i])
+			buf.WriteByte(':')
+			n.values[i].writeCompact(buf)
+		}
+		buf.WriteByte('}')
+	case '[':
+		buf.WriteByte('[')
+		for i, value := range n.values {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			value.writeCompact(buf)
+		}
+		buf.WriteByte(']')
+	default:
+		buf.Write(n.raw)
+	}
+}
+
+// notebookParser builds jsonNode trees from notebook files
+type notebookParser struct {
+	data    []byte
+	decoder *json.Decoder
+}
+
+// parseNotebook parses notebook JSON into an order-preserving tree
+func parseNotebook(data []byte) (*jsonNode, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	parser := &notebookParser{data: data, decoder: decoder}
+
+	root, err := parser.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if root.kind != '{' {
+		return nil, errInvalidNotebook
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after notebook JSON")
+	}
+	return root, nil
+}
+
+// nextToken reads the next token and returns it with its raw encoding
+func (np *notebookParser) nextToken() (json.Token, []byte, error) {
+	start := np.decoder.InputOffset()
+	token, err := np.decoder.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	end := np.decoder.InputOffset()
+	// The span between tokens includes whitespace and the ',' and ':' separators
+	raw := bytes.TrimLeft(np.data[start:end], " \t\r\n,:")
+	return token, raw, nil
+}
+
+// parseValue parses the next JSON value
+func (np *notebookParser) parseValue() (*jsonNode, error) {
+	token, raw, err := np.nextToken()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return &jsonNode{raw: raw}, nil
+	}
+
+	node := &jsonNode{kind: byte(delim)}
+	for np.decoder.More() {
+		if node.kind == '{' {
+			keyToken, rawKey, err := np.nextToken()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyToken.(string)
+			node.keys = append(node.keys, key)
+			node.rawKeys = append(node.rawKeys, rawKey)
+		}
+		value, err := np.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		node.values = append(node.values, value)
+	}
+
+	// Consume the closing delimiter
+	if _, _, err := np.nextToken(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// detectIndent returns the indentation unit used by a JSON document, or ""
+// when the document is written on a single line
+func detectIndent(data []byte) string {
+	newline := bytes.IndexByte(data, newlineByte)
+	if newline < 0 {
+		return ""
+	}
+	rest := data[newline+1:]
+	end := 0
+	for end < len(rest) && (rest[end] == ' ' || rest[end] == '\t') {
+		end++
+	}
+	return string(rest[:end])
+}
+
+// encodeNotebook serializes the tree using the given indentation, without a final newline
+func encodeNotebook(root *jsonNode, indent string) ([]byte, error) {
+	var compact bytes.Buffer
+	root.writeCompact(&compact)
+	if indent == "" {
+		return compact.Bytes(), nil
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), "", indent); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// restoreNotebookLayout gives an encoded notebook the line endings and the trailing
+// line breaks of the original file, so that only the policy changes them
+func restoreNotebookLayout(encoded, original []byte) []byte {
+	var counter lineEndingCounter
+	_, _ = counter.Write(original)
+	if fixed, change := (&lineEndingFixer{target: counter.dominant()}).fix(encoded); change != "" {
+		encoded = fixed
+	}
+	return append(encoded, original[len(bytes.TrimRight(original, "\r\n")):]...)
+}
+
+// splitSourceLines splits text into lines that keep their terminators, as nbformat stores them
+func splitSourceLines(text string) []string {
+	lines := []string{}
+	for line := range strings.SplitAfterSeq(text, "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// normalizeCellSource strips trailing newlines from a cell source, which may be
+// a string or a list of lines. It returns nil when the source needs no change.
+func normalizeCellSource(source *jsonNode) (*jsonNode, error) {
+	switch source.kind {
+	case '[':
+		var lines []string
+		var compact bytes.Buffer
+		source.writeCompact(&compact)
+		if err := json.Unmarshal(compact.Bytes(), &lines); err != nil {
+			return nil, err
+		}
+		normalized := splitSourceLines(strings.TrimRight(strings.Join(lines, ""), "\r\n"))
+		if slices.Equal(lines, normalized) {
+			return nil, nil
+		}
+		return &jsonNode{raw: encodeSourceLines(normalized)}, nil
+	default:
+		var text string
+		if err := json.Unmarshal(source.raw, &text); err != nil {
+			return nil, err
+		}
+		normalized := strings.TrimRight(text, "\r\n")
+		if normalized == text {
+			return nil, nil
+		}
+		return &jsonNode{raw: encodeJSONString(normalized)}, nil
+	}
+}
+
+// encodeSourceLines encodes cell source lines as a compact JSON array
+func encodeSourceLines(lines []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, line := range lines {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(encodeJSONString(line))
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// encodeJSONString encodes s the way nbformat does (Python's json.dumps with
+// ensure_ascii=False): only quotes, backslashes and control characters are escaped
+func encodeJSONString(s string) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.Bytes()
+}
+
+// normalizeNotebookCells normalizes the source of every cell, returning the number changed
+func normalizeNotebookCells(root *jsonNode) (int, error) {
+	cells := root.get("cells")
+	if cells == nil || cells.kind != '[' {
+		return 0, nil
+	}
+
+	changed := 0
+	for _, cell := range cells.values {
+		source := cell.get("source")
+		if source == nil {
+			continue
+		}
+		normalized, err := normalizeCellSource(source)
+		if err != nil {
+			return 0, fmt.Errorf("invalid cell source: %w", err)
+		}
+		if normalized != nil {
+			cell.set("source", normalized)
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// fixNotebookIfNeeded normalizes a notebook's cell sources, then applies the file's
+// policy to the result like to any other file. When no cell changes, or the notebook
+// is not plain UTF-8 text, the file is handled like any other.
+func fixNotebookIfNeeded(logger logging.Logger, filePath string, options processOptions, result *FileResult) error {
+	if options.keepNotebookCells || !shouldProcessFile(filePath) {
+		return addNewlineIfNeeded(logger, filePath, options, result)
+	}
+
+	policy := options.policies.resolve(filePath)
+	head, err := readHead(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read notebook: %w", err)
+	}
+	encoding, hasBOM := detectEncoding(head)
+	if hasBOM || encoding.isWide() || charsetEncodings[policy.Charset] != nil || detectBinary(head, encoding) != "" {
+		logger.Debug("│ Notebook is not plain UTF-8 text, leaving cells alone")
+		return addNewlineIfNeeded(logger, filePath, options, result)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read notebook: %w", err)
+	}
+
+	root, err := parseNotebook(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse notebook: %w", err)
+	}
+
+	changed, err := normalizeNotebookCells(root)
+	if err != nil {
+		return err
+	}
+	if changed == 0 {
+		logger.Debug("│ Notebook cells already normalized")
+		return addNewlineIfNeeded(logger, filePath, options, result)
+	}
+
+	encoded, err := encodeNotebook(root, detectIndent(data))
+	if err != nil {
+		return fmt.Errorf("failed to encode notebook: %w", err)
+	}
+	updated, policyChanges := fixTextContent(filePath, policy, string(restoreNotebookLayout(encoded, data)))
+	scanContent(logger, policy, []byte(updated), result)
+	changes := append([]string{fmt.Sprintf(changeNormalizedCells, changed)}, policyChanges...)
+
+	if options.dryRun {
+		logger.Debug(fmt.Sprintf("│ %d notebook cell(s) need normalizing (dry run, not modified)", changed))
+		result.Changes = append(result.Changes, changes...)
+		return nil
+	}
+
+	logger.Debug(fmt.Sprintf("│ Normalizing %d notebook cell(s)", changed))
+	if err := os.WriteFile(filePath, []byte(updated), filePermission); err != nil {
+		return fmt.Errorf("failed to write notebook: %w", err)
+	}
+
+	result.Changes = append(result.Changes, changes...)
+	logger.Info(fmt.Sprintf("Normalized notebook cells in %s", filePath))
+	return nil
+}
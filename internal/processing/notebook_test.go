@@ -0,0 +1,281 @@
+package processing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+// sampleNotebook is laid out the way nbformat writes notebooks: one-space
+// indentation, non-ASCII text unescaped and a final newline
+const sampleNotebook = `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "metadata": {},
+   "source": [
+    "# Título <b>\n",
+    "\n",
+    "text "
+   ]
+  },
+  {
+   "cell_type": "code",
+   "execution_count": 1.0,
+   "metadata": {
+    "tags": []
+   },
+   "outputs": [],
+   "source": "x = 1"
+  }
+ ],
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`
+
+func TestIsNotebook(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "analysis.ipynb", expected: true},
+		{path: "/dir/Analysis.IPYNB", expected: true},
+		{path: "notes.txt", expected: false},
+		{path: "ipynb", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if result := isNotebook(tt.path); result != tt.expected {
+				t.Errorf("isNotebook(%q) = %v, want %v", tt.path, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNotebookRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "nbformat layout", input: sampleNotebook},
+		{name: "two space indent", input: "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": {}\n}\n"},
+		{name: "compact", input: "{\"b\":1,\"a\":[true,null]}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parseNotebook([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("parseNotebook() error = %v", err)
+			}
+			output, err := encodeNotebook(root, detectIndent([]byte(tt.input)))
+			if err != nil {
+				t.Fatalf("encodeNotebook() error = %v", err)
+			}
+			if string(output) != strings.TrimSuffix(tt.input, "\n") {
+				t.Errorf("Round trip changed notebook:\ngot:\n%s\nwant:\n%s", output, tt.input)
+			}
+		})
+	}
+}
+
+func TestParseNotebookInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "not JSON", input: "not json"},
+		{name: "array", input: "[1, 2]"},
+		{name: "truncated", input: `{"cells": [`},
+		{name: "trailing data", input: `{"cells": []} {}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseNotebook([]byte(tt.input)); err == nil {
+				t.Error("Expected error for invalid notebook")
+			}
+		})
+	}
+}
+
+func TestNormalizeCellSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{name: "normalized list", source: `["a\n","b"]`, expected: ""},
+		{name: "trailing newline in list", source: `["a\n","b\n"]`, expected: `["a\n","b"]`},
+		{name: "trailing empty lines in list", source: `["a\n","\n","\n"]`, expected: `["a"]`},
+		{name: "only newlines in list", source: `["\n"]`, expected: `[]`},
+		{name: "empty list", source: `[]`, expected: ""},
+		{name: "normalized string", source: `"a\nb"`, expected: ""},
+		{name: "trailing newline in string", source: `"a\nb\r\n"`, expected: `"a\nb"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parseNotebook([]byte(`{"source":` + tt.source + `}`))
+			if err != nil {
+				t.Fatalf("parseNotebook() error = %v", err)
+			}
+			normalized, err := normalizeCellSource(root.get("source"))
+			if err != nil {
+				t.Fatalf("normalizeCellSource() error = %v", err)
+			}
+
+			if tt.expected == "" {
+				if normalized != nil {
+					t.Errorf("normalizeCellSource() = %s, want no change", normalized.raw)
+				}
+				return
+			}
+			if normalized == nil || string(normalized.raw) != tt.expected {
+				t.Errorf("normalizeCellSource() = %v, want %s", normalized, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFixNotebookIfNeeded(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name          string
+		options       processOptions
+		input         string
+		expected      string
+		expectChanges int
+	}{
+		{
+			name:          "normalized notebook",
+			input:         sampleNotebook,
+			expected:      sampleNotebook,
+			expectChanges: 0,
+		},
+		{
+			name:          "missing final newline only",
+			input:         strings.TrimSuffix(sampleNotebook, "\n"),
+			expected:      sampleNotebook,
+			expectChanges: 1,
+		},
+		{
+			name:          "cell with trailing newline",
+			input:         strings.Replace(sampleNotebook, `"source": "x = 1"`, `"source": "x = 1\n\n"`, 1),
+			expected:      sampleNotebook,
+			expectChanges: 1,
+		},
+		{
+			name:          "cell and final newline",
+			input:         strings.TrimSuffix(strings.Replace(sampleNotebook, `"text "`, `"text \n"`, 1), "\n"),
+			expected:      sampleNotebook,
+			expectChanges: 2,
+		},
+		{
+			name:          "keep notebook cells",
+			options:       processOptions{keepNotebookCells: true},
+			input:         strings.Replace(sampleNotebook, `"source": "x = 1"`, `"source": "x = 1\n"`, 1),
+			expected:      strings.Replace(sampleNotebook, `"source": "x = 1"`, `"source": "x = 1\n"`, 1),
+			expectChanges: 0,
+		},
+		{
+			name:          "cell changed with final newline none",
+			options:       processOptions{policies: newPolicyResolver(cli.PolicySet{Policy: cli.Policy{FinalNewline: cli.FinalNewlineNone}})},
+			input:         strings.Replace(sampleNotebook, `"source": "x = 1"`, `"source": "x = 1\n"`, 1),
+			expected:      strings.TrimSuffix(sampleNotebook, "\n"),
+			expectChanges: 2,
+		},
+		{
+			name:          "cell changed with final newline exactly one",
+			options:       processOptions{policies: newPolicyResolver(cli.PolicySet{Policy: cli.Policy{FinalNewline: cli.FinalNewlineExactlyOne}})},
+			input:         strings.Replace(sampleNotebook, `"source": "x = 1"`, `"source": "x = 1\n"`, 1) + "\n\n",
+			expected:      sampleNotebook,
+			expectChanges: 2,
+		},
+		{
+			name:          "cell changed keeps CRLF line endings",
+			input:         strings.ReplaceAll(strings.TrimSuffix(strings.Replace(sampleNotebook, `"text "`, `"text \n"`, 1), "\n"), "\n", "\r\n"),
+			expected:      strings.ReplaceAll(sampleNotebook, "\n", "\r\n"),
+			expectChanges: 2,
+		},
+		{
+			name:          "cell changed with end of line lf",
+			options:       processOptions{policies: newPolicyResolver(cli.PolicySet{Policy: cli.Policy{EndOfLine: cli.EndOfLineLF}})},
+			input:         strings.ReplaceAll(strings.Replace(sampleNotebook, `"source": "x = 1"`, `"source": "x = 1\n"`, 1), "\n", "\r\n"),
+			expected:      sampleNotebook,
+			expectChanges: 2,
+		},
+		{
+			name:          "dry run",
+			options:       processOptions{dryRun: true},
+			input:         strings.TrimSuffix(strings.Replace(sampleNotebook, `"source": "x = 1"`, `"source": "x = 1\n"`, 1), "\n"),
+			expected:      strings.TrimSuffix(strings.Replace(sampleNotebook, `"source": "x = 1"`, `"source": "x = 1\n"`, 1), "\n"),
+			expectChanges: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(tempDir, tt.name+".ipynb")
+			if err := os.WriteFile(filePath, []byte(tt.input), 0o644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			result := FileResult{Path: filePath}
+			if err := fixNotebookIfNeeded(&mockLogger{}, filePath, tt.options, &result); err != nil {
+				t.Fatalf("fixNotebookIfNeeded() error = %v", err)
+			}
+
+			content, _ := os.ReadFile(filePath)
+			if string(content) != tt.expected {
+				t.Errorf("Notebook content:\n%s\nwant:\n%s", content, tt.expected)
+			}
+			if len(result.Changes) != tt.expectChanges {
+				t.Errorf("Changes = %v, want %d changes", result.Changes, tt.expectChanges)
+			}
+		})
+	}
+}
+
+func TestFixNotebookIfNeededInvalid(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "broken.ipynb")
+	_ = os.WriteFile(filePath, []byte(`{"cells": [`), 0o644)
+
+	result := FileResult{Path: filePath}
+	if err := fixNotebookIfNeeded(&mockLogger{}, filePath, processOptions{}, &result); err == nil {
+		t.Error("Expected error for invalid notebook")
+	}
+
+	content, _ := os.ReadFile(filePath)
+	if string(content) != `{"cells": [` {
+		t.Errorf("Invalid notebook should not be modified, got %q", content)
+	}
+}
+
+func TestEncodeJSONString(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "plain", input: "x = 1", expected: `"x = 1"`},
+		{name: "quotes and backslashes", input: `say "hi" \o/`, expected: `"say \"hi\" \\o/"`},
+		{name: "control characters", input: "a\tb\nc\r\x01\b\f", expected: `"a\tb\nc\r\u0001\b\f"`},
+		{name: "html characters", input: "<b>&</b>", expected: `"<b>&</b>"`},
+		{name: "non-ASCII", input: "Título \u2028 日本", expected: "\"Título \u2028 日本\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := string(encodeJSONString(tt.input)); result != tt.expected {
+				t.Errorf("encodeJSONString() = %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
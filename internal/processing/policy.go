@@ -0,0 +1,92 @@
+package processing
+
+import (
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+// defaultPolicy applies to files that no configured policy says otherwise about
+var defaultPolicy = cli.Policy{
+	FinalNewline: cli.FinalNewlineEnsure,
+	BOM:          cli.BOMPreserve,
+}
+
+// mergePolicy returns base with the fields set in override replacing its own
+func mergePolicy(base, override cli.Policy) cli.Policy {
+	if override.FinalNewline != "" {
+		base.FinalNewline = override.FinalNewline
+	}
+	if override.TrimTrailingWhitespace != nil {
+		base.TrimTrailingWhitespace = override.TrimTrailingWhitespace
+	}
+	if override.BOM != "" {
+		base.BOM = override.BOM
+	}
+	if override.EndOfLine != "" {
+		base.EndOfLine = override.EndOfLine
+	}
+	if override.IndentStyle != "" {
+		base.IndentStyle = override.IndentStyle
+	}
+	if override.IndentSize != 0 {
+		base.IndentSize = override.IndentSize
+	}
+	if override.UnicodeCleanup != nil {
+		base.UnicodeCleanup = override.UnicodeCleanup
+	}
+	if override.AllowTypographic != nil {
+		base.AllowTypographic = override.AllowTypographic
+	}
+	if override.TrimLeadingBlankLines != nil {
+		base.TrimLeadingBlankLines = override.TrimLeadingBlankLines
+	}
+	if override.MaxBlankLines != nil {
+		base.MaxBlankLines = override.MaxBlankLines
+	}
+	if override.ControlCharacters != "" {
+		base.ControlCharacters = override.ControlCharacters
+	}
+	if override.Charset != "" {
+		base.Charset = override.Charset
+	}
+	if override.TranscodeUTF8 != nil {
+		base.TranscodeUTF8 = override.TranscodeUTF8
+	}
+	if override.ProcessBinary != nil {
+		base.ProcessBinary = override.ProcessBinary
+	}
+	return base
+}
+
+// policyResolver decides the policy of each file from the configured policy set
+type policyResolver struct {
+	defaults cli.Policy
+	rules    []cli.Rule
+}
+
+// newPolicyResolver creates a policy resolver for the configured policy set
+func newPolicyResolver(set cli.PolicySet) *policyResolver {
+	return &policyResolver{
+		defaults: mergePolicy(defaultPolicy, set.Policy),
+		rules:    set.Rules,
+	}
+}
+
+// resolve returns the policy of a file: the configured defaults refined by every
+// rule whose pattern matches, in order. A nil resolver yields the built-in defaults.
+func (pr *policyResolver) resolve(filePath string) cli.Policy {
+	if pr == nil {
+		return defaultPolicy
+	}
+	policy := pr.defaults
+	for _, rule := range pr.rules {
+		if newGlobPatternMatcher([]string{rule.Pattern}).matches(filePath) {
+			policy = mergePolicy(policy, rule.Policy)
+		}
+	}
+	return policy
+}
+
+// enabled reports whether an optional policy switch is set and true
+func enabled(value *bool) bool {
+	return value != nil && *value
+}
@@ -0,0 +1,55 @@
+package processing
+
+import (
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+func TestPolicyResolverResolve(t *testing.T) {
+	set := cli.PolicySet{
+		Policy: cli.Policy{FinalNewline: cli.FinalNewlineExactlyOne},
+		Rules: []cli.Rule{
+			{Pattern: "*.md", Policy: cli.Policy{FinalNewline: cli.FinalNewlineEnsure}},
+			{Pattern: "README.*", Policy: cli.Policy{FinalNewline: cli.FinalNewlineExactlyOne}},
+			{Pattern: "*.txt"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		resolver *policyResolver
+		filePath string
+		expected string
+	}{
+		{name: "nil resolver", resolver: nil, filePath: "/a/main.go", expected: cli.FinalNewlineEnsure},
+		{name: "empty policy set", resolver: newPolicyResolver(cli.PolicySet{}), filePath: "/a/main.go", expected: cli.FinalNewlineEnsure},
+		{name: "configured default", resolver: newPolicyResolver(set), filePath: "/a/main.go", expected: cli.FinalNewlineExactlyOne},
+		{name: "matching rule", resolver: newPolicyResolver(set), filePath: "/a/doc.md", expected: cli.FinalNewlineEnsure},
+		{name: "later rule wins", resolver: newPolicyResolver(set), filePath: "/a/README.md", expected: cli.FinalNewlineExactlyOne},
+		{name: "rule without the field inherits", resolver: newPolicyResolver(set), filePath: "/a/notes.txt", expected: cli.FinalNewlineExactlyOne},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.resolver.resolve(tt.filePath); result.FinalNewline != tt.expected {
+				t.Errorf("resolve(%q).FinalNewline = %q, want %q", tt.filePath, result.FinalNewline, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMergePolicy(t *testing.T) {
+	on, off := true, false
+	base := cli.Policy{FinalNewline: cli.FinalNewlineEnsure, TrimTrailingWhitespace: &on}
+
+	result := mergePolicy(base, cli.Policy{})
+	if result.FinalNewline != cli.FinalNewlineEnsure || !enabled(result.TrimTrailingWhitespace) {
+		t.Errorf("mergePolicy() with empty override = %+v, want base unchanged", result)
+	}
+
+	result = mergePolicy(base, cli.Policy{FinalNewline: cli.FinalNewlineExactlyOne, TrimTrailingWhitespace: &off})
+	if result.FinalNewline != cli.FinalNewlineExactlyOne || enabled(result.TrimTrailingWhitespace) {
+		t.Errorf("mergePolicy() = %+v, want override fields applied", result)
+	}
+}
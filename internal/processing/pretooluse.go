@@ -0,0 +1,272 @@
+package processing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/hookoutput"
+	"github.com/koh-sh/ccnewline/internal/logging"
+	"github.com/koh-sh/ccnewline/internal/toolinput"
+)
+
+// preToolUseEvent is the hook event name of PreToolUse responses
+const preToolUseEvent = "PreToolUse"
+
+// autoApprovedModes are permission modes in which file edits are approved without prompting,
+// so allowing an updated input does not grant anything the session would not already allow
+var autoApprovedModes = []string{"acceptEdits", "bypassPermissions"}
+
+// editSimulator predicts file content after Edit and MultiEdit calls
+type editSimulator struct{}
+
+// apply performs a single replacement the way the Edit tool does
+func (es *editSimulator) apply(content string, edit toolinput.EditOperation) string {
+	if edit.ReplaceAll {
+		return strings.ReplaceAll(content, edit.OldString, edit.NewString)
+	}
+	return strings.Replace(content, edit.OldString, edit.NewString, 1)
+}
+
+// touchesEnd reports whether the replacement includes the end of the content
+func (es *editSimulator) touchesEnd(content string, edit toolinput.EditOperation) bool {
+	if edit.OldString == "" {
+		return content == ""
+	}
+	index := strings.Index(content, edit.OldString)
+	if edit.ReplaceAll {
+		index = strings.LastIndex(content, edit.OldString)
+	}
+	return index >= 0 && index+len(edit.OldString) == len(content)
+}
+
+// applyAll performs the edits in order
+func (es *editSimulator) applyAll(content string, edits []toolinput.EditOperation) string {
+	for _, edit := range edits {
+		content = es.apply(content, edit)
+	}
+	return content
+}
+
+// fixEdits appends a line ending to the new_string of the edit that produces the end
+// of the file, when the edited file would otherwise lack a final line break.
+// It returns the index of the fixed edit, or -1 when no fix is needed or possible.
+func (es *editSimulator) fixEdits(original string, edits []toolinput.EditOperation) int {
+	content := original
+	last := -1
+	for i, edit := range edits {
+		if es.touchesEnd(content, edit) {
+			last = i
+		}
+		content = es.apply(content, edit)
+	}
+
+	if content == "" || last < 0 || edits[last].NewString == "" {
+		return -1
+	}
+	ending := missingEndingFromContent([]byte(content))
+	if ending == "" {
+		return -1
+	}
+
+	// Verify that the fix yields exactly the expected content before using it
+	fixed := make([]toolinput.EditOperation, len(edits))
+	copy(fixed, edits)
+	fixed[last].NewString += ending
+	if es.applyAll(original, fixed) != content+ending {
+		return -1
+	}
+	edits[last].NewString = fixed[last].NewString
+	return last
+}
+
+// inputFixer computes corrected tool input for PreToolUse
+type inputFixer struct {
+	logger    logging.Logger
+	simulator *editSimulator
+	options   processOptions
+}
+
+// newInputFixer creates a new input fixer
+func newInputFixer(logger logging.Logger, options processOptions) *inputFixer {
+	return &inputFixer{
+		logger:    logger,
+		simulator: &editSimulator{},
+		options:   options,
+	}
+}
+
+// fix returns the tool input fields to update and a description of the change,
+// or nil when the input needs no change. filePath is the resolved target file.
+func (inf *inputFixer) fix(hook *toolinput.HookInput, filePath string) (map[string]any, string, error) {
+	switch hook.ToolName {
+	case toolinput.ToolWrite:
+		in, err := hook.WriteInput()
+		if err != nil {
+			return nil, "", err
+		}
+		if in.Content == "" {
+			return nil, "", nil
+		}
+		content, changes := inf.fixContent(filePath, in.Content)
+		if len(changes) == 0 {
+			return nil, "", nil
+		}
+		return map[string]any{"content": content}, strings.Join(changes, ", "), nil
+
+	case toolinput.ToolEdit:
+		in, err := hook.EditInput()
+		if err != nil {
+			return nil, "", err
+		}
+		edits := []toolinput.EditOperation{{OldString: in.OldString, NewString: in.NewString, ReplaceAll: in.ReplaceAll}}
+		if !inf.wantsFinalNewline(filePath) || inf.simulator.fixEdits(inf.readCurrent(filePath), edits) < 0 {
+			return nil, "", nil
+		}
+		return map[string]any{"new_string": edits[0].NewString}, changeAddedNewline, nil
+
+	case toolinput.ToolMultiEdit:
+		in, err := hook.MultiEditInput()
+		if err != nil {
+			return nil, "", err
+		}
+		if !inf.wantsFinalNewline(filePath) || inf.simulator.fixEdits(inf.readCurrent(filePath), in.Edits) < 0 {
+			return nil, "", nil
+		}
+		return map[string]any{"edits": in.Edits}, changeAddedNewline, nil
+
+	case toolinput.ToolNotebookEdit:
+		in, err := hook.NotebookEditInput()
+		if err != nil {
+			return nil, "", err
+		}
+		normalized := strings.TrimRight(in.NewSource, "\r\n")
+		if inf.options.keepNotebookCells || normalized == in.NewSource {
+			return nil, "", nil
+		}
+		return map[string]any{"new_source": normalized}, fmt.Sprintf(changeNormalizedCells, 1), nil
+	}
+
+	inf.logger.Debug(fmt.Sprintf("No input fix for %s tool", hook.ToolName))
+	return nil, "", nil
+}
+
+// fixContent applies the file's policy to the content a Write call is about to write,
+// returning the fixed content and the changes made. Preserving the byte order mark
+// keeps that of the file being overwritten.
+func (inf *inputFixer) fixContent(filePath, content string) (string, []string) {
+	policy := inf.options.policies.resolve(filePath)
+	if policy.BOM == cli.BOMPreserve {
+		policy.BOM = currentBOMPolicy(filePath)
+	}
+	return fixTextContent(filePath, policy, content)
+}
+
+// wantsFinalNewline reports whether the file's policy asks for a final newline,
+// which edits are then fixed to keep
+func (inf *inputFixer) wantsFinalNewline(filePath string) bool {
+	if inf.options.policies.resolve(filePath).FinalNewline == cli.FinalNewlineNone {
+		inf.logger.Debug(fmt.Sprintf("│ %s must not end with a newline, leaving edits alone", filePath))
+		return false
+	}
+	return true
+}
+
+// readCurrent returns the current content of the file, or "" if it cannot be read
+func (inf *inputFixer) readCurrent(filePath string) string {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		inf.logger.Debug(fmt.Sprintf("│ Cannot read %s: %v", filePath, err))
+		return ""
+	}
+	return string(data)
+}
+
+// mergeToolInput overlays the updated fields on the original tool input
+func mergeToolInput(hook *toolinput.HookInput, updates map[string]any) (json.RawMessage, error) {
+	var input map[string]any
+	if err := hook.DecodeToolInput(&input); err != nil {
+		return nil, err
+	}
+	for key, value := range updates {
+		input[key] = value
+	}
+	return marshalNoEscape(input)
+}
+
+// marshalNoEscape encodes v as compact JSON without HTML escaping
+func marshalNoEscape(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// permissionDecision chooses how Claude Code should treat the updated call. Allowing
+// would skip the user's permission prompt, so it is only used when the session
+// already approves edits automatically. Otherwise the decision is left to Claude Code's
+// permission rules, or the user is asked when askPermission is set.
+func permissionDecision(hook *toolinput.HookInput, askPermission bool) string {
+	switch {
+	case slices.Contains(autoApprovedModes, hook.PermissionMode):
+		return hookoutput.PermissionAllow
+	case askPermission:
+		return hookoutput.PermissionAsk
+	}
+	return ""
+}
+
+// buildPreToolUseResponse fixes the tool input before it is written and returns the
+// response carrying the updated input, or nil when the input needs no change
+func buildPreToolUseResponse(logger logging.Logger, hook *toolinput.HookInput, filePath string, options processOptions) (*hookoutput.Response, error) {
+	updates, change, err := newInputFixer(logger, options).fix(hook, filePath)
+	if err != nil || updates == nil {
+		return nil, err
+	}
+
+	updatedInput, err := mergeToolInput(hook, updates)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug(fmt.Sprintf("│ Updating %s input: %s", hook.ToolName, change))
+	return &hookoutput.Response{
+		SuppressOutput: true,
+		HookSpecificOutput: &hookoutput.HookSpecificOutput{
+			HookEventName:            preToolUseEvent,
+			PermissionDecision:       permissionDecision(hook, options.askPermission),
+			PermissionDecisionReason: fmt.Sprintf("ccnewline %s in %s", change, filePath),
+			UpdatedInput:             updatedInput,
+		},
+	}, nil
+}
+
+// runPreToolUse responds to a PreToolUse payload with corrected tool input instead
+// of modifying files. It returns the number of files that passed the filters.
+func runPreToolUse(config *cli.Config, logger logging.Logger, hook *toolinput.HookInput, filePaths []string) int {
+	if hook == nil || len(filePaths) != 1 {
+		logger.Debug("PreToolUse mode needs a hook payload for a single file")
+		return 0
+	}
+
+	filePath := filePaths[0]
+	if !newFileFilter(config).shouldProcess(filePath) {
+		logger.Debug(fmt.Sprintf("Skipping %s (filtered)", filePath))
+		return 0
+	}
+
+	resp, err := buildPreToolUseResponse(logger, hook, filePath, newProcessOptions(config))
+	if err != nil {
+		newErrorHandler().handleError(logger, filePath, err)
+		return 1
+	}
+	newResponseWriter().write(logger, resp)
+	return 1
+}
@@ -0,0 +1,424 @@
+package processing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/hookoutput"
+	"github.com/koh-sh/ccnewline/internal/toolinput"
+)
+
+func TestEditSimulatorFixEdits(t *testing.T) {
+	tests := []struct {
+		name        string
+		original    string
+		edits       []toolinput.EditOperation
+		expectIndex int
+		expectNew   string
+	}{
+		{
+			name:        "edit at end of file",
+			original:    "a\nb\n",
+			edits:       []toolinput.EditOperation{{OldString: "b\n", NewString: "c"}},
+			expectIndex: 0,
+			expectNew:   "c\n",
+		},
+		{
+			name:        "edit at end of CRLF file",
+			original:    "a\r\nb\r\n",
+			edits:       []toolinput.EditOperation{{OldString: "b\r\n", NewString: "c"}},
+			expectIndex: 0,
+			expectNew:   "c\r\n",
+		},
+		{
+			name:        "edit keeps newline",
+			original:    "a\nb\n",
+			edits:       []toolinput.EditOperation{{OldString: "b\n", NewString: "c\n"}},
+			expectIndex: -1,
+		},
+		{
+			name:        "edit in the middle of file without newline",
+			original:    "a\nb",
+			edits:       []toolinput.EditOperation{{OldString: "a", NewString: "x"}},
+			expectIndex: -1,
+		},
+		{
+			name:        "edit creating a new file",
+			original:    "",
+			edits:       []toolinput.EditOperation{{OldString: "", NewString: "package main"}},
+			expectIndex: 0,
+			expectNew:   "package main\n",
+		},
+		{
+			name:        "edit deleting the end",
+			original:    "a\nb",
+			edits:       []toolinput.EditOperation{{OldString: "\nb", NewString: ""}},
+			expectIndex: -1,
+		},
+		{
+			// Appending to new_string would add a newline after every replacement
+			name:     "replace all touching end",
+			original: "x y x",
+			edits: []toolinput.EditOperation{
+				{OldString: "x", NewString: "z", ReplaceAll: true},
+			},
+			expectIndex: -1,
+		},
+		{
+			name:     "multi edit where last tail edit is fixed",
+			original: "one\ntwo\n",
+			edits: []toolinput.EditOperation{
+				{OldString: "two\n", NewString: "three"},
+				{OldString: "one", NewString: "uno"},
+			},
+			expectIndex: 0,
+			expectNew:   "three\n",
+		},
+		{
+			name:     "multi edit with later edit of the tail",
+			original: "one\ntwo\n",
+			edits: []toolinput.EditOperation{
+				{OldString: "two\n", NewString: "three"},
+				{OldString: "three", NewString: "four"},
+			},
+			expectIndex: 1,
+			expectNew:   "four\n",
+		},
+	}
+
+	simulator := &editSimulator{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := make([]toolinput.EditOperation, len(tt.edits))
+			copy(edits, tt.edits)
+
+			index := simulator.fixEdits(tt.original, edits)
+			if index != tt.expectIndex {
+				t.Fatalf("fixEdits() = %v, want %v", index, tt.expectIndex)
+			}
+			if index >= 0 && edits[index].NewString != tt.expectNew {
+				t.Errorf("NewString = %q, want %q", edits[index].NewString, tt.expectNew)
+			}
+		})
+	}
+}
+
+func TestBuildPreToolUseResponse(t *testing.T) {
+	tempDir := t.TempDir()
+	existing := filepath.Join(tempDir, "existing.go")
+	_ = os.WriteFile(existing, []byte("package main\n\nfunc main() {}\n"), 0o644)
+
+	tests := []struct {
+		name           string
+		toolName       string
+		permissionMode string
+		askPermission  bool
+		toolInput      string
+		expectNil      bool
+		expectField    string
+		expectValue    any
+		expectDecision string
+	}{
+		{
+			name:           "write without newline",
+			toolName:       "Write",
+			toolInput:      `{"file_path": "` + existing + `", "content": "package main"}`,
+			expectField:    "content",
+			expectValue:    "package main\n",
+			expectDecision: "",
+		},
+		{
+			name:           "write CRLF content",
+			toolName:       "Write",
+			toolInput:      `{"file_path": "` + existing + `", "content": "a\r\nb"}`,
+			expectField:    "content",
+			expectValue:    "a\r\nb\r\n",
+			expectDecision: "",
+		},
+		{
+			name:           "write when asking for permission",
+			toolName:       "Write",
+			askPermission:  true,
+			toolInput:      `{"file_path": "` + existing + `", "content": "package main"}`,
+			expectField:    "content",
+			expectValue:    "package main\n",
+			expectDecision: hookoutput.PermissionAsk,
+		},
+		{
+			name:           "bypass permissions mode allows even when asking",
+			toolName:       "Write",
+			permissionMode: "bypassPermissions",
+			askPermission:  true,
+			toolInput:      `{"file_path": "` + existing + `", "content": "package main"}`,
+			expectField:    "content",
+			expectValue:    "package main\n",
+			expectDecision: hookoutput.PermissionAllow,
+		},
+		{
+			name:      "write with newline",
+			toolName:  "Write",
+			toolInput: `{"file_path": "` + existing + `", "content": "package main\n"}`,
+			expectNil: true,
+		},
+		{
+			name:      "write empty content",
+			toolName:  "Write",
+			toolInput: `{"file_path": "` + existing + `", "content": ""}`,
+			expectNil: true,
+		},
+		{
+			name:           "edit at end in accept edits mode",
+			toolName:       "Edit",
+			permissionMode: "acceptEdits",
+			toolInput:      `{"file_path": "` + existing + `", "old_string": "func main() {}\n", "new_string": "func main() {\n}"}`,
+			expectField:    "new_string",
+			expectValue:    "func main() {\n}\n",
+			expectDecision: hookoutput.PermissionAllow,
+		},
+		{
+			name:      "edit in the middle",
+			toolName:  "Edit",
+			toolInput: `{"file_path": "` + existing + `", "old_string": "package main", "new_string": "package app"}`,
+			expectNil: true,
+		},
+		{
+			name:           "notebook edit with trailing newline",
+			toolName:       "NotebookEdit",
+			toolInput:      `{"notebook_path": "/nb.ipynb", "new_source": "x = 1\n\n"}`,
+			expectField:    "new_source",
+			expectValue:    "x = 1",
+			expectDecision: "",
+		},
+		{
+			name:      "other tool",
+			toolName:  "Bash",
+			toolInput: `{"command": "ls"}`,
+			expectNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := &toolinput.HookInput{
+				ToolName:       tt.toolName,
+				PermissionMode: tt.permissionMode,
+				ToolInput:      json.RawMessage(tt.toolInput),
+			}
+
+			resp, err := buildPreToolUseResponse(&mockLogger{}, hook, existing, processOptions{askPermission: tt.askPermission})
+			if err != nil {
+				t.Fatalf("buildPreToolUseResponse() error = %v", err)
+			}
+			if (resp == nil) != tt.expectNil {
+				t.Fatalf("buildPreToolUseResponse() = %v, expectNil %v", resp, tt.expectNil)
+			}
+			if resp == nil {
+				return
+			}
+
+			output := resp.HookSpecificOutput
+			if output.HookEventName != "PreToolUse" {
+				t.Errorf("HookEventName = %v, want PreToolUse", output.HookEventName)
+			}
+			if output.PermissionDecision != tt.expectDecision {
+				t.Errorf("PermissionDecision = %v, want %v", output.PermissionDecision, tt.expectDecision)
+			}
+
+			var updated map[string]any
+			if err := json.Unmarshal(output.UpdatedInput, &updated); err != nil {
+				t.Fatalf("UpdatedInput is not valid JSON: %v", err)
+			}
+			if updated[tt.expectField] != tt.expectValue {
+				t.Errorf("UpdatedInput[%s] = %q, want %q", tt.expectField, updated[tt.expectField], tt.expectValue)
+			}
+			// Fields that were not fixed are passed through unchanged
+			if _, ok := updated["file_path"]; !ok && tt.toolName != "NotebookEdit" {
+				t.Error("UpdatedInput should keep file_path")
+			}
+		})
+	}
+}
+
+func TestBuildPreToolUseResponseMultiEdit(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "file.txt")
+	_ = os.WriteFile(filePath, []byte("one\ntwo\n"), 0o644)
+
+	hook := &toolinput.HookInput{
+		ToolName: "MultiEdit",
+		ToolInput: json.RawMessage(`{"file_path": "` + filePath + `", "edits": [
+			{"old_string": "one", "new_string": "uno"},
+			{"old_string": "two\n", "new_string": "dos"}
+		]}`),
+	}
+
+	resp, err := buildPreToolUseResponse(&mockLogger{}, hook, filePath, processOptions{})
+	if err != nil || resp == nil {
+		t.Fatalf("buildPreToolUseResponse() = %v, %v", resp, err)
+	}
+
+	var updated toolinput.MultiEditInput
+	if err := json.Unmarshal(resp.HookSpecificOutput.UpdatedInput, &updated); err != nil {
+		t.Fatalf("UpdatedInput is not valid JSON: %v", err)
+	}
+	if len(updated.Edits) != 2 || updated.Edits[0].NewString != "uno" || updated.Edits[1].NewString != "dos\n" {
+		t.Errorf("Edits = %+v", updated.Edits)
+	}
+}
+
+func TestRunPreToolUseDoesNotModifyFiles(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "file.txt")
+	_ = os.WriteFile(filePath, []byte("content"), 0o644)
+
+	hook := &toolinput.HookInput{
+		ToolName:  "Write",
+		ToolInput: json.RawMessage(`{"file_path": "` + filePath + `", "content": "new"}`),
+	}
+
+	// Capture stdout, where the response is written
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	processed := runPreToolUse(&cli.Config{}, &mockLogger{}, hook, []string{filePath})
+	w.Close()
+	os.Stdout = oldStdout
+
+	output, _ := io.ReadAll(r)
+
+	if processed != 1 {
+		t.Errorf("runPreToolUse() = %v, want 1", processed)
+	}
+	if !strings.Contains(string(output), `"updatedInput"`) {
+		t.Errorf("Expected updatedInput in output, got %q", output)
+	}
+	content, _ := os.ReadFile(filePath)
+	if string(content) != "content" {
+		t.Errorf("File should not be modified in PreToolUse mode, got %q", content)
+	}
+}
+
+func TestBuildPreToolUseResponseExactlyOne(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "file.txt")
+	options := processOptions{
+		policies: newPolicyResolver(cli.PolicySet{Policy: cli.Policy{FinalNewline: cli.FinalNewlineExactlyOne}}),
+	}
+
+	tests := []struct {
+		name         string
+		content      string
+		expectNil    bool
+		expectValue  string
+		expectReason string
+	}{
+		{name: "trailing blank lines", content: "a\n\n\n", expectValue: "a\n", expectReason: "removed 2 trailing blank line(s)"},
+		{name: "CRLF trailing blank lines", content: "a\r\n\r\n", expectValue: "a\r\n", expectReason: "removed 1 trailing blank line(s)"},
+		{name: "missing newline", content: "a", expectValue: "a\n", expectReason: changeAddedNewline},
+		{name: "single newline", content: "a\n", expectNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := json.Marshal(map[string]string{"file_path": filePath, "content": tt.content})
+			hook := &toolinput.HookInput{ToolName: "Write", ToolInput: input}
+
+			resp, err := buildPreToolUseResponse(&mockLogger{}, hook, filePath, options)
+			if err != nil {
+				t.Fatalf("buildPreToolUseResponse() error = %v", err)
+			}
+			if (resp == nil) != tt.expectNil {
+				t.Fatalf("buildPreToolUseResponse() = %v, expectNil %v", resp, tt.expectNil)
+			}
+			if resp == nil {
+				return
+			}
+
+			var updated toolinput.WriteInput
+			if err := json.Unmarshal(resp.HookSpecificOutput.UpdatedInput, &updated); err != nil {
+				t.Fatalf("UpdatedInput is not valid JSON: %v", err)
+			}
+			if updated.Content != tt.expectValue {
+				t.Errorf("Content = %q, want %q", updated.Content, tt.expectValue)
+			}
+			if !strings.Contains(resp.HookSpecificOutput.PermissionDecisionReason, tt.expectReason) {
+				t.Errorf("PermissionDecisionReason = %q, want it to contain %q", resp.HookSpecificOutput.PermissionDecisionReason, tt.expectReason)
+			}
+		})
+	}
+}
+
+func TestBuildPreToolUseResponseTrimsWhitespace(t *testing.T) {
+	on := true
+	filePath := filepath.Join(t.TempDir(), "main.go")
+	options := processOptions{
+		policies: newPolicyResolver(cli.PolicySet{Policy: cli.Policy{TrimTrailingWhitespace: &on}}),
+	}
+	input, _ := json.Marshal(map[string]string{"file_path": filePath, "content": "package main \nfunc main() {}"})
+	hook := &toolinput.HookInput{ToolName: "Write", ToolInput: input}
+
+	resp, err := buildPreToolUseResponse(&mockLogger{}, hook, filePath, options)
+	if err != nil || resp == nil {
+		t.Fatalf("buildPreToolUseResponse() = %v, %v", resp, err)
+	}
+
+	var updated toolinput.WriteInput
+	if err := json.Unmarshal(resp.HookSpecificOutput.UpdatedInput, &updated); err != nil {
+		t.Fatalf("UpdatedInput is not valid JSON: %v", err)
+	}
+	if updated.Content != "package main\nfunc main() {}\n" {
+		t.Errorf("Content = %q", updated.Content)
+	}
+	expectedReason := "trimmed trailing whitespace on 1 line(s), " + changeAddedNewline
+	if !strings.Contains(resp.HookSpecificOutput.PermissionDecisionReason, expectedReason) {
+		t.Errorf("PermissionDecisionReason = %q, want it to contain %q", resp.HookSpecificOutput.PermissionDecisionReason, expectedReason)
+	}
+}
+
+func TestBuildPreToolUseResponseNone(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "expected.golden")
+	_ = os.WriteFile(filePath, []byte("old"), 0o644)
+	options := processOptions{
+		policies: newPolicyResolver(cli.PolicySet{Policy: cli.Policy{FinalNewline: cli.FinalNewlineNone}}),
+	}
+
+	tests := []struct {
+		name        string
+		toolName    string
+		toolInput   map[string]string
+		expectNil   bool
+		expectValue string
+	}{
+		{name: "write with newline", toolName: "Write", toolInput: map[string]string{"file_path": filePath, "content": "new\n\n"}, expectValue: "new"},
+		{name: "write without newline", toolName: "Write", toolInput: map[string]string{"file_path": filePath, "content": "new"}, expectNil: true},
+		{name: "edit at end", toolName: "Edit", toolInput: map[string]string{"file_path": filePath, "old_string": "old", "new_string": "new"}, expectNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := json.Marshal(tt.toolInput)
+			hook := &toolinput.HookInput{ToolName: tt.toolName, ToolInput: input}
+
+			resp, err := buildPreToolUseResponse(&mockLogger{}, hook, filePath, options)
+			if err != nil {
+				t.Fatalf("buildPreToolUseResponse() error = %v", err)
+			}
+			if (resp == nil) != tt.expectNil {
+				t.Fatalf("buildPreToolUseResponse() = %v, expectNil %v", resp, tt.expectNil)
+			}
+			if resp == nil {
+				return
+			}
+
+			var updated toolinput.WriteInput
+			if err := json.Unmarshal(resp.HookSpecificOutput.UpdatedInput, &updated); err != nil {
+				t.Fatalf("UpdatedInput is not valid JSON: %v", err)
+			}
+			if updated.Content != tt.expectValue {
+				t.Errorf("Content = %q, want %q", updated.Content, tt.expectValue)
+			}
+		})
+	}
+}
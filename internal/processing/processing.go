@@ -3,10 +3,12 @@
 package processing
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/koh-sh/ccnewline/internal/cli"
 	"github.com/koh-sh/ccnewline/internal/logging"
@@ -22,6 +24,16 @@ const (
 	filePermission = 0o644
 )
 
+// Exit codes returned by Run
+const (
+	// exitOK reports success
+	exitOK = 0
+	// exitBlocking makes Claude Code feed stderr back to the model as blocking feedback
+	exitBlocking = 2
+	// exitInputError reports input that could not be read or parsed
+	exitInputError = 3
+)
+
 // patternMatcher defines the interface for pattern matching
 type patternMatcher interface {
 	matches(path string) bool
@@ -82,6 +94,62 @@ func (ff *fileFilter) shouldProcess(filePath string) bool {
 	return true
 }
 
+// processOptions holds settings that affect how individual files are processed
+type processOptions struct {
+	// keepNotebookCells disables normalization of notebook cell sources
+	keepNotebookCells bool
+	// dryRun records the changes a file needs without writing them
+	dryRun bool
+	// policies decides the policy of each file; nil applies the built-in defaults
+	policies *policyResolver
+	// askPermission makes corrected PreToolUse calls prompt for permission
+	askPermission bool
+}
+
+// newProcessOptions creates process options from the configuration
+func newProcessOptions(config *cli.Config) processOptions {
+	return processOptions{
+		keepNotebookCells: config.KeepNotebookCells,
+		dryRun:            config.Enforce,
+		policies:          newPolicyResolver(config.Policies),
+		askPermission:     config.AskPermission,
+	}
+}
+
+// newReadOptions creates input reading options from the configuration
+func newReadOptions(config *cli.Config) toolinput.Options {
+	return toolinput.Options{
+		MaxSize:       config.MaxInputSize,
+		Strict:        config.Strict,
+		PathsOnly:     config.PathsFromStdin,
+		NullSeparated: config.NullSeparated,
+		// Only PreToolUse decodes the tool arguments, which hold whole file contents
+		DiscardToolInput: config.Event != cli.EventPreToolUse,
+	}
+}
+
+// toolSelector decides which tools' calls are acted on
+type toolSelector struct {
+	tools []string
+}
+
+// newToolSelector creates a tool selector from the configuration
+func newToolSelector(config *cli.Config) *toolSelector {
+	return &toolSelector{tools: config.Tools}
+}
+
+// accepts reports whether calls of the named tool should be processed
+func (ts *toolSelector) accepts(toolName string) bool {
+	// Input without tool metadata (plain text, bare payloads) is always processed
+	if toolName == "" {
+		return true
+	}
+	if len(ts.tools) > 0 {
+		return slices.Contains(ts.tools, toolName)
+	}
+	return !toolinput.IsReadOnlyTool(toolName)
+}
+
 // errorHandler handles error processing and reporting
 type errorHandler struct {
 	ErrorWriter io.Writer
@@ -111,38 +179,44 @@ func (pl *progressLogger) logProgress(logger logging.Logger, processed, total in
 // singleFileProcessor handles processing of individual files
 type singleFileProcessor struct {
 	logger       logging.Logger
+	options      processOptions
 	errorHandler *errorHandler
 	progress     *progressLogger
 }
 
 // newSingleFileProcessor creates a new single file processor
-func newSingleFileProcessor(logger logging.Logger) *singleFileProcessor {
+func newSingleFileProcessor(logger logging.Logger, options processOptions) *singleFileProcessor {
 	return &singleFileProcessor{
 		logger:       logger,
+		options:      options,
 		errorHandler: newErrorHandler(),
 		progress:     &progressLogger{},
 	}
 }
 
 // process handles the processing of a single file
-func (sfp *singleFileProcessor) process(filePath string, processed, total int) {
+func (sfp *singleFileProcessor) process(filePath string, processed, total int) FileResult {
 	sfp.progress.logProgress(sfp.logger, processed, total, filePath)
 
-	if err := processSingleFile(sfp.logger, filePath); err != nil {
+	result, err := processSingleFile(sfp.logger, filePath, sfp.options)
+	if err != nil {
 		sfp.errorHandler.handleError(sfp.logger, filePath, err)
 	}
+	return result
 }
 
 // fileProcessor handles the main file processing logic
 type fileProcessor struct {
+	options   processOptions
 	validator *fileValidator
 	checker   *newlineChecker
 	modifier  *fileModifier
 }
 
 // newFileProcessor creates a new file processor
-func newFileProcessor() *fileProcessor {
+func newFileProcessor(options processOptions) *fileProcessor {
 	return &fileProcessor{
+		options:   options,
 		validator: &fileValidator{},
 		checker:   &newlineChecker{},
 		modifier:  &fileModifier{},
@@ -159,56 +233,164 @@ type newlineChecker struct{}
 type fileModifier struct{}
 
 // processFile processes a single file for newline addition
-func (fp *fileProcessor) processFile(logger logging.Logger, filePath string) error {
-	return addNewlineIfNeeded(logger, filePath)
+func (fp *fileProcessor) processFile(logger logging.Logger, filePath string) (FileResult, error) {
+	result := FileResult{Path: filePath}
+	var err error
+	if isNotebook(filePath) {
+		err = fixNotebookIfNeeded(logger, filePath, fp.options, &result)
+	} else {
+		err = addNewlineIfNeeded(logger, filePath, fp.options, &result)
+	}
+	return result, err
 }
 
 // ProcessFiles processes multiple files, adding newlines where needed
-func ProcessFiles(logger logging.Logger, filePaths []string, filter *fileFilter) int {
-	processor := newSingleFileProcessor(logger)
-	processedCount := 0
+func ProcessFiles(logger logging.Logger, filePaths []string, filter *fileFilter, options processOptions) *Report {
+	processor := newSingleFileProcessor(logger, options)
+	report := &Report{}
 
 	for _, filePath := range filePaths {
 		if !filter.shouldProcess(filePath) {
 			logger.Debug(fmt.Sprintf("Skipping %s (filtered)", filePath))
+			report.add(FileResult{Path: filePath, Skipped: skipFiltered})
 			continue
 		}
 
-		processedCount++
-		processor.process(filePath, processedCount, len(filePaths))
+		report.Processed++
+		report.add(processor.process(filePath, report.Processed, len(filePaths)))
+	}
+
+	return report
+}
+
+// Run executes the main processing logic with the given configuration and input,
+// returning the exit code of the process. Each payload of a payload stream is
+// handled on its own, and the most severe exit code wins.
+func Run(config *cli.Config, logger logging.Logger, input io.Reader) int {
+	exitCode := exitOK
+	payloads, err := toolinput.ReadPayloads(logger, input, newReadOptions(config))
+	if err != nil {
+		reportInputError(logger, err)
+		exitCode = exitInputError
+	}
+	if len(payloads) == 0 {
+		payloads = []toolinput.Payload{{}}
 	}
 
-	return processedCount
+	for _, payload := range payloads {
+		exitCode = max(exitCode, runPayload(config, logger, payload))
+	}
+	return exitCode
 }
 
-// Run executes the main processing logic with the given configuration and input
-func Run(config *cli.Config, logger logging.Logger, input io.Reader) {
-	filePaths := toolinput.ReadToolInput(logger, input)
+// reportInputError explains why the input could not be read in full
+func reportInputError(logger logging.Logger, err error) {
+	if errors.Is(err, toolinput.ErrInputTooLarge) {
+		logger.Error(fmt.Sprintf("Error reading input: %v; raise the limit with --max-input-size", err))
+		return
+	}
+	logger.Error(fmt.Sprintf("Error reading input: %v", err))
+}
+
+// runPayload processes the files of a single payload, returning its exit code
+func runPayload(config *cli.Config, logger logging.Logger, payload toolinput.Payload) int {
+	filePaths, hook := payload.Paths, payload.Hook
+	switch {
+	case config.Event == cli.EventStop:
+		filePaths = readSessionPaths(config, logger, hook)
+	case hook != nil && !newToolSelector(config).accepts(hook.ToolName):
+		logger.Debug(fmt.Sprintf("Ignoring %s tool call", hook.ToolName))
+		filePaths = nil
+	}
 	logger.ShowProcessingStart(filePaths)
 
 	if len(filePaths) == 0 {
 		logger.ShowProcessingEnd(0, 0)
-		return
+		return exitOK
+	}
+
+	if config.Event == cli.EventPreToolUse {
+		processed := runPreToolUse(config, logger, hook, filePaths)
+		logger.ShowProcessingEnd(len(filePaths), processed)
+		return exitOK
 	}
 
 	filter := newFileFilter(config)
-	processedCount := ProcessFiles(logger, filePaths, filter)
-	logger.ShowProcessingEnd(len(filePaths), processedCount)
+	report := ProcessFiles(logger, filePaths, filter, newProcessOptions(config))
+	logger.ShowProcessingEnd(len(filePaths), report.Processed)
+	logSkipped(logger, report)
+
+	if config.Enforce {
+		return newEnforcer().enforce(logger, report, hook)
+	}
+	logFindings(logger, report)
+
+	if config.Output == cli.OutputJSON {
+		newResponseWriter().write(logger, buildHookResponse(report, hook))
+	}
+	return exitOK
 }
 
 // processSingleFile processes a single file, adding a newline if needed
-func processSingleFile(logger logging.Logger, filePath string) error {
-	processor := newFileProcessor()
+func processSingleFile(logger logging.Logger, filePath string, options processOptions) (FileResult, error) {
+	processor := newFileProcessor(options)
 	return processor.processFile(logger, filePath)
 }
 
-// addNewlineIfNeeded adds a newline to a file if it doesn't already end with one
-func addNewlineIfNeeded(logger logging.Logger, filePath string) error {
+// addNewlineIfNeeded adds a newline to a file if it doesn't already end with one.
+// The content fixers the file's policy enables run first, and under the exactly-one
+// policy trailing blank lines are collapsed before the final newline is checked.
+// Under the none policy the final newline is removed instead. UTF-16 and UTF-32
+// files and files of a declared charset are handled in their own encoding, and
+// binary files are skipped.
+func addNewlineIfNeeded(logger logging.Logger, filePath string, options processOptions, result *FileResult) error {
 	if !shouldProcessFile(filePath) {
 		logger.Debug("│ File does not exist, skipping")
+		result.Skipped = skipMissing
 		return nil
 	}
 
+	policy := options.policies.resolve(filePath)
+	head, err := readHead(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	encoding, hasBOM := detectEncoding(head)
+	if charset := charsetEncodings[policy.Charset]; charset != nil {
+		// A byte order mark or zero bytes establish a UTF encoding other than the declared one
+		if hasBOM || encoding.isWide() {
+			logger.Debug(fmt.Sprintf("│ Skipping: declared %s but detected %s", charset.name, encoding.name))
+			result.Skipped = fmt.Sprintf(skipUndecodable, charset.name)
+			return nil
+		}
+		encoding = charset
+	}
+	if reason := detectBinary(head, encoding); reason != "" && !enabled(policy.ProcessBinary) {
+		logger.Debug(fmt.Sprintf("│ Skipped: binary (%s)", reason))
+		result.Skipped = skipBinary
+		return nil
+	}
+	if encoding.codec != nil {
+		logger.Debug(fmt.Sprintf("│ Declared %s encoding", encoding.name))
+		return fixCharsetTextIfNeeded(logger, filePath, encoding, policy, options, result)
+	}
+	if encoding.isWide() {
+		logger.Debug(fmt.Sprintf("│ Detected %s encoding", encoding.name))
+		return fixWideTextIfNeeded(logger, filePath, encoding, hasBOM, policy, options, result)
+	}
+
+	if err := fixContentIfNeeded(logger, filePath, policy, options, result); err != nil {
+		return err
+	}
+	switch policy.FinalNewline {
+	case cli.FinalNewlineNone:
+		return removeFinalNewline(logger, filePath, options, result)
+	case cli.FinalNewlineExactlyOne:
+		if err := collapseTrailingBlankLines(logger, filePath, options, result); err != nil {
+			return err
+		}
+	}
+
 	needsNewline, err := checkLastByte(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to check file: %w", err)
@@ -219,13 +401,29 @@ func addNewlineIfNeeded(logger logging.Logger, filePath string) error {
 		return nil
 	}
 
-	logger.Debug("│ Adding newline (missing)")
+	ending, err := missingEndingFromFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to check line endings: %w", err)
+	}
+	if ending == "" {
+		logger.Debug("│ Already ends with a carriage return line break")
+		return nil
+	}
+
+	if options.dryRun {
+		logger.Debug("│ Newline missing (dry run, not modified)")
+		result.Changes = append(result.Changes, changeAddedNewline)
+		return nil
+	}
+
+	logger.Debug(fmt.Sprintf("│ Adding newline %q (missing)", ending))
 
-	if err := addNewlineToFile(filePath); err != nil {
+	if err := addNewlineToFile(filePath, ending); err != nil {
 		return fmt.Errorf("failed to add newline: %w", err)
 	}
 
 	logger.Debug("│ Newline added successfully")
+	result.Changes = append(result.Changes, changeAddedNewline)
 	logger.Info(fmt.Sprintf("Added newline to %s", filePath))
 	return nil
 }
@@ -259,15 +457,15 @@ func checkLastByte(filePath string) (bool, error) {
 	return lastByte[0] != newlineByte, nil
 }
 
-// addNewlineToFile appends a newline character to the end of a file
-func addNewlineToFile(filePath string) error {
+// addNewlineToFile appends a line ending, such as "\n" or "\r\n", to the end of a file
+func addNewlineToFile(filePath, ending string) error {
 	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND, filePermission)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	_, err = file.Write([]byte{newlineByte})
+	_, err = file.WriteString(ending)
 	return err
 }
 
@@ -307,23 +307,33 @@ func TestAddNewlineToFile(t *testing.T) {
 	tests := []struct {
 		name           string
 		initialContent []byte
+		ending         string
 		expectContent  []byte
 	}{
 		{
 			name:           "add newline to file without one",
 			initialContent: []byte("hello"),
+			ending:         "\n",
 			expectContent:  []byte("hello\n"),
 		},
 		{
 			name:           "add newline to empty file",
 			initialContent: []byte{},
+			ending:         "\n",
 			expectContent:  []byte("\n"),
 		},
 		{
 			name:           "add newline to file with existing newline",
 			initialContent: []byte("hello\n"),
+			ending:         "\n",
 			expectContent:  []byte("hello\n\n"),
 		},
+		{
+			name:           "add CRLF",
+			initialContent: []byte("hello\r\nworld"),
+			ending:         "\r\n",
+			expectContent:  []byte("hello\r\nworld\r\n"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -334,7 +344,7 @@ func TestAddNewlineToFile(t *testing.T) {
 				t.Fatalf("Failed to create test file: %v", err)
 			}
 
-			err = addNewlineToFile(filePath)
+			err = addNewlineToFile(filePath, tt.ending)
 			if err != nil {
 				t.Errorf("addNewlineToFile() error = %v", err)
 			}
@@ -352,7 +362,7 @@ func TestAddNewlineToFile(t *testing.T) {
 }
 
 func TestAddNewlineToFileWithNonExistentFile(t *testing.T) {
-	err := addNewlineToFile("/non/existent/file.txt")
+	err := addNewlineToFile("/non/existent/file.txt", "\n")
 	if err == nil {
 		t.Error("Expected error for non-existent file")
 	}
@@ -455,10 +465,10 @@ func TestProcessFiles(t *testing.T) {
 			logger := &mockLogger{}
 			filter := newFileFilter(tt.config)
 
-			processedCount := ProcessFiles(logger, tt.filePaths, filter)
+			report := ProcessFiles(logger, tt.filePaths, filter, newProcessOptions(tt.config))
 
-			if processedCount != tt.expectedCount {
-				t.Errorf("ProcessFiles() = %v, want %v", processedCount, tt.expectedCount)
+			if report.Processed != tt.expectedCount {
+				t.Errorf("ProcessFiles() = %v, want %v", report.Processed, tt.expectedCount)
 			}
 
 			actualSkips := 0
@@ -579,7 +589,7 @@ func TestProcessSingleFile(t *testing.T) {
 			originalContent, _ := os.ReadFile(filePath)
 			logger := &mockLogger{}
 
-			err = processSingleFile(logger, filePath)
+			_, err = processSingleFile(logger, filePath, processOptions{})
 			if err != nil {
 				t.Errorf("processSingleFile() error = %v", err)
 			}
@@ -603,9 +613,169 @@ func TestProcessSingleFile(t *testing.T) {
 
 func TestProcessSingleFileWithNonExistentFile(t *testing.T) {
 	logger := &mockLogger{}
-	err := processSingleFile(logger, "/non/existent/file.txt")
+	_, err := processSingleFile(logger, "/non/existent/file.txt", processOptions{})
 	// Should not return error for non-existent file (just skip processing)
 	if err != nil {
 		t.Errorf("Unexpected error for non-existent file: %v", err)
 	}
 }
+
+func TestToolSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		tools    []string
+		toolName string
+		expected bool
+	}{
+		{name: "no tool name", toolName: "", expected: true},
+		{name: "default accepts Write", toolName: "Write", expected: true},
+		{name: "default accepts NotebookEdit", toolName: "NotebookEdit", expected: true},
+		{name: "default accepts unknown tool", toolName: "mcp__fs__write_file", expected: true},
+		{name: "default ignores Read", toolName: "Read", expected: false},
+		{name: "default ignores Grep", toolName: "Grep", expected: false},
+		{name: "configured tool", tools: []string{"Write"}, toolName: "Write", expected: true},
+		{name: "unconfigured tool", tools: []string{"Write"}, toolName: "Edit", expected: false},
+		{name: "configured read-only tool", tools: []string{"Read"}, toolName: "Read", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := newToolSelector(&cli.Config{Tools: tt.tools})
+			if result := selector.accepts(tt.toolName); result != tt.expected {
+				t.Errorf("accepts(%q) = %v, want %v", tt.toolName, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunIgnoresReadOnlyTools(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.txt")
+	_ = os.WriteFile(testFile, []byte("content"), 0o644)
+
+	tests := []struct {
+		name           string
+		toolName       string
+		expectModified bool
+	}{
+		{name: "Read is ignored", toolName: "Read", expectModified: false},
+		{name: "Write is processed", toolName: "Write", expectModified: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.WriteFile(testFile, []byte("content"), 0o644)
+			logger := &mockLogger{}
+			input := `{"tool_name": "` + tt.toolName + `", "tool_input": {"file_path": "` + testFile + `"}}`
+
+			Run(&cli.Config{Silent: true}, logger, strings.NewReader(input))
+
+			content, _ := os.ReadFile(testFile)
+			modified := string(content) != "content"
+			if modified != tt.expectModified {
+				t.Errorf("File modification = %v, want %v", modified, tt.expectModified)
+			}
+		})
+	}
+}
+
+func TestRunPayloadStream(t *testing.T) {
+	tempDir := t.TempDir()
+	dirA := filepath.Join(tempDir, "a")
+	dirB := filepath.Join(tempDir, "b")
+	_ = os.Mkdir(dirA, 0o755)
+	_ = os.Mkdir(dirB, 0o755)
+	_ = os.WriteFile(filepath.Join(dirA, "file.txt"), []byte("content"), 0o644)
+	_ = os.WriteFile(filepath.Join(dirB, "file.txt"), []byte("content"), 0o644)
+
+	// Each payload resolves its relative path against its own cwd and is filtered by its own tool
+	input := `{"tool_name": "Write", "cwd": "` + dirA + `", "tool_input": {"file_path": "file.txt"}}` + "\n" +
+		`{"tool_name": "Read", "cwd": "` + dirB + `", "tool_input": {"file_path": "file.txt"}}` + "\n"
+
+	Run(&cli.Config{Silent: true}, &mockLogger{}, strings.NewReader(input))
+
+	if content, _ := os.ReadFile(filepath.Join(dirA, "file.txt")); string(content) != "content\n" {
+		t.Errorf("Written file = %q, want newline added", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dirB, "file.txt")); string(content) != "content" {
+		t.Errorf("Read file should not be modified, got %q", content)
+	}
+}
+
+func TestRunInputTooLarge(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	_ = os.WriteFile(testFile, []byte("content"), 0o644)
+
+	logger := &mockLogger{}
+	input := `{"tool_input": {"file_path": "` + testFile + `", "content": "` + strings.Repeat("x", 1024) + `"}}`
+	code := Run(&cli.Config{Silent: true, MaxInputSize: 512}, logger, strings.NewReader(input))
+
+	if code != exitInputError {
+		t.Errorf("Run() = %v, want %v", code, exitInputError)
+	}
+	found := false
+	for _, msg := range logger.errorMessages {
+		if strings.Contains(msg, "--max-input-size") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected error mentioning --max-input-size, got %v", logger.errorMessages)
+	}
+}
+
+func TestRunStrictInput(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+
+	tests := []struct {
+		name       string
+		config     *cli.Config
+		input      string
+		expectCode int
+		expectFix  bool
+	}{
+		{
+			name:       "strict rejects plain text",
+			config:     &cli.Config{Silent: true, Strict: true},
+			input:      testFile,
+			expectCode: exitInputError,
+			expectFix:  false,
+		},
+		{
+			name:       "strict rejects truncated payload",
+			config:     &cli.Config{Silent: true, Strict: true},
+			input:      `{"tool_input": {"file_path": "` + testFile,
+			expectCode: exitInputError,
+			expectFix:  false,
+		},
+		{
+			name:       "paths from stdin",
+			config:     &cli.Config{Silent: true, Strict: true, PathsFromStdin: true},
+			input:      testFile + "\n",
+			expectCode: exitOK,
+			expectFix:  true,
+		},
+		{
+			name:       "NUL-separated paths",
+			config:     &cli.Config{Silent: true, PathsFromStdin: true, NullSeparated: true},
+			input:      testFile + "\x00",
+			expectCode: exitOK,
+			expectFix:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.WriteFile(testFile, []byte("content"), 0o644)
+
+			code := Run(tt.config, &mockLogger{}, strings.NewReader(tt.input))
+			if code != tt.expectCode {
+				t.Errorf("Run() = %v, want %v", code, tt.expectCode)
+			}
+			content, _ := os.ReadFile(testFile)
+			if (string(content) == "content\n") != tt.expectFix {
+				t.Errorf("File content = %q, expectFix %v", content, tt.expectFix)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package processing
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/koh-sh/ccnewline/internal/hookoutput"
+	"github.com/koh-sh/ccnewline/internal/logging"
+	"github.com/koh-sh/ccnewline/internal/toolinput"
+)
+
+// Descriptions recorded in file results
+const (
+	// changeAddedNewline records that a final newline was appended
+	changeAddedNewline = "added final newline"
+
+	// skipFiltered records that include/exclude patterns excluded the file
+	skipFiltered = "filtered"
+	// skipMissing records that the file does not exist or is empty
+	skipMissing = "missing or empty"
+	// skipBinary records that the file holds binary data rather than text
+	skipBinary = "binary"
+
+	// maxLocatedFindings is the number of findings per file listed in hook responses
+	maxLocatedFindings = 20
+
+	// defaultHookEvent is assumed when the input does not name its hook event
+	defaultHookEvent = "PostToolUse"
+)
+
+// FileResult describes what happened to a single file
+type FileResult struct {
+	// Path is the file that was examined
+	Path string
+	// Changes lists the modifications made to the file
+	Changes []string
+	// Findings lists problems found in the file that were left for the user to fix,
+	// as "line:column: description"
+	Findings []string
+	// Skipped is the reason the file was not examined, empty if it was
+	Skipped string
+}
+
+// Modified reports whether any change was made to the file
+func (fr *FileResult) Modified() bool {
+	return len(fr.Changes) > 0
+}
+
+// describe formats the result as "path: change, change"
+func (fr *FileResult) describe() string {
+	return fmt.Sprintf("%s: %s", fr.Path, strings.Join(fr.Changes, ", "))
+}
+
+// locateFindings formats the findings as "path:line:column: description",
+// summarizing those past maxLocatedFindings in a final entry
+func (fr *FileResult) locateFindings() []string {
+	var located []string
+	for i, finding := range fr.Findings {
+		if i == maxLocatedFindings {
+			located = append(located, fmt.Sprintf("%s: and %d more", fr.Path, len(fr.Findings)-i))
+			break
+		}
+		located = append(located, fmt.Sprintf("%s:%s", fr.Path, finding))
+	}
+	return located
+}
+
+// Report summarizes the outcome of processing a batch of files
+type Report struct {
+	// Processed is the number of files that passed the filters
+	Processed int
+	// Results holds per-file outcomes in processing order
+	Results []FileResult
+}
+
+// add appends a file result to the report
+func (r *Report) add(result FileResult) {
+	r.Results = append(r.Results, result)
+}
+
+// Modified returns the results of files that were changed
+func (r *Report) Modified() []FileResult {
+	var modified []FileResult
+	for _, result := range r.Results {
+		if result.Modified() {
+			modified = append(modified, result)
+		}
+	}
+	return modified
+}
+
+// Flagged returns the results of files with findings left for the user to fix
+func (r *Report) Flagged() []FileResult {
+	var flagged []FileResult
+	for _, result := range r.Results {
+		if len(result.Findings) > 0 {
+			flagged = append(flagged, result)
+		}
+	}
+	return flagged
+}
+
+// Skipped returns the results of files that were skipped, such as filtered or binary files
+func (r *Report) Skipped() []FileResult {
+	var skipped []FileResult
+	for _, result := range r.Results {
+		if result.Skipped != "" {
+			skipped = append(skipped, result)
+		}
+	}
+	return skipped
+}
+
+// Sentences introducing and closing the findings left for Claude to fix
+const (
+	findingsHeader = "ccnewline found characters it did not fix:"
+	findingsFooter = "Remove or replace them unless they are intended."
+)
+
+// logFindings prints the findings left for the user to fix, one "path:line:column: description" per line
+func logFindings(logger logging.Logger, report *Report) {
+	for _, result := range report.Flagged() {
+		for _, finding := range result.locateFindings() {
+			logger.Info(finding)
+		}
+	}
+}
+
+// logSkipped lists the skipped files and why in the debug summary
+func logSkipped(logger logging.Logger, report *Report) {
+	for _, result := range report.Skipped() {
+		logger.Debug(fmt.Sprintf("Skipped %s: %s", result.Path, result.Skipped))
+	}
+}
+
+// buildHookResponse summarizes the modified and flagged files for Claude and the user.
+// It returns nil when there is nothing to tell, in which case no response is needed.
+func buildHookResponse(report *Report, hook *toolinput.HookInput) *hookoutput.Response {
+	modified, flagged := report.Modified(), report.Flagged()
+	if len(modified) == 0 && len(flagged) == 0 {
+		return nil
+	}
+
+	eventName := defaultHookEvent
+	if hook != nil && hook.HookEventName != "" {
+		eventName = hook.HookEventName
+	}
+
+	descriptions := make([]string, 0, len(modified))
+	for _, result := range modified {
+		descriptions = append(descriptions, result.describe())
+	}
+
+	var context strings.Builder
+	if len(modified) > 0 {
+		context.WriteString("ccnewline modified files after the tool call, so their content on disk differs from what was written:\n")
+		for _, description := range descriptions {
+			context.WriteString("- " + description + "\n")
+		}
+		context.WriteString("Take these changes into account before editing the files again.")
+	}
+	if len(flagged) > 0 {
+		if context.Len() > 0 {
+			context.WriteString("\n")
+		}
+		context.WriteString(findingsHeader + "\n")
+		for _, result := range flagged {
+			for _, finding := range result.locateFindings() {
+				context.WriteString("- " + finding + "\n")
+			}
+			descriptions = append(descriptions, fmt.Sprintf("%s: %d character problem(s) left to fix", result.Path, len(result.Findings)))
+		}
+		context.WriteString(findingsFooter)
+	}
+
+	resp := &hookoutput.Response{
+		SuppressOutput: true,
+		SystemMessage:  "ccnewline: " + strings.Join(descriptions, "; "),
+	}
+	// Stop hooks cannot add context, since Claude has already finished its turn
+	if !slices.Contains(stopHookEvents, eventName) {
+		resp.HookSpecificOutput = &hookoutput.HookSpecificOutput{
+			HookEventName:     eventName,
+			AdditionalContext: context.String(),
+		}
+	}
+	return resp
+}
+
+// responseWriter writes hook JSON responses
+type responseWriter struct {
+	Writer io.Writer
+}
+
+// newResponseWriter creates a response writer for stdout
+func newResponseWriter() *responseWriter {
+	return &responseWriter{
+		Writer: os.Stdout,
+	}
+}
+
+// write outputs the response, doing nothing when it is nil
+func (rw *responseWriter) write(logger logging.Logger, resp *hookoutput.Response) {
+	if resp == nil {
+		logger.Debug("No changes to report in hook response")
+		return
+	}
+	if err := hookoutput.Write(rw.Writer, resp); err != nil {
+		logger.Error(fmt.Sprintf("Error writing hook response: %v", err))
+	}
+}
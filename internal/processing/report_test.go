@@ -0,0 +1,175 @@
+package processing
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/toolinput"
+)
+
+func TestReportModified(t *testing.T) {
+	report := &Report{}
+	report.add(FileResult{Path: "a.txt", Changes: []string{changeAddedNewline}})
+	report.add(FileResult{Path: "b.txt"})
+	report.add(FileResult{Path: "c.txt", Skipped: skipFiltered})
+
+	modified := report.Modified()
+	if len(modified) != 1 || modified[0].Path != "a.txt" {
+		t.Errorf("Modified() = %v, want only a.txt", modified)
+	}
+}
+
+func TestReportSkipped(t *testing.T) {
+	report := &Report{}
+	report.add(FileResult{Path: "a.txt", Changes: []string{changeAddedNewline}})
+	report.add(FileResult{Path: "b.png", Skipped: skipBinary})
+	report.add(FileResult{Path: "c.txt", Skipped: skipFiltered})
+
+	skipped := report.Skipped()
+	if len(skipped) != 2 || skipped[0].Path != "b.png" || skipped[1].Path != "c.txt" {
+		t.Errorf("Skipped() = %v, want b.png and c.txt", skipped)
+	}
+
+	logger := &mockLogger{}
+	logSkipped(logger, report)
+	if len(logger.debugMessages) != 2 || logger.debugMessages[0] != "Skipped b.png: binary" {
+		t.Errorf("logSkipped() debug messages = %v", logger.debugMessages)
+	}
+}
+
+func TestReportFlagged(t *testing.T) {
+	report := &Report{}
+	report.add(FileResult{Path: "a.txt", Changes: []string{changeAddedNewline}})
+	report.add(FileResult{Path: "b.go", Findings: []string{"1:6: U+201C left double quotation mark"}})
+
+	flagged := report.Flagged()
+	if len(flagged) != 1 || flagged[0].Path != "b.go" {
+		t.Errorf("Flagged() = %v, want only b.go", flagged)
+	}
+}
+
+func TestFileResultLocateFindings(t *testing.T) {
+	result := FileResult{Path: "a.go", Findings: []string{"1:6: U+201C left double quotation mark"}}
+	expected := []string{"a.go:1:6: U+201C left double quotation mark"}
+	if located := result.locateFindings(); !reflect.DeepEqual(located, expected) {
+		t.Errorf("locateFindings() = %v, want %v", located, expected)
+	}
+
+	result.Findings = make([]string, maxLocatedFindings+3)
+	located := result.locateFindings()
+	if len(located) != maxLocatedFindings+1 || located[maxLocatedFindings] != "a.go: and 3 more" {
+		t.Errorf("locateFindings() = %d entries ending in %q, want the rest summarized", len(located), located[len(located)-1])
+	}
+}
+
+func TestBuildHookResponseFindings(t *testing.T) {
+	report := &Report{Results: []FileResult{{Path: "a.go", Findings: []string{"1:6: U+201C left double quotation mark"}}}}
+	resp := buildHookResponse(report, nil)
+	if resp == nil {
+		t.Fatal("buildHookResponse() = nil, want a response for findings")
+	}
+	if resp.SystemMessage != "ccnewline: a.go: 1 character problem(s) left to fix" {
+		t.Errorf("SystemMessage = %q", resp.SystemMessage)
+	}
+	context := resp.HookSpecificOutput.AdditionalContext
+	if !strings.Contains(context, "- a.go:1:6: U+201C left double quotation mark") || strings.Contains(context, "modified files") {
+		t.Errorf("AdditionalContext = %q", context)
+	}
+}
+
+func TestBuildHookResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		results     []FileResult
+		hook        *toolinput.HookInput
+		expectNil   bool
+		expectEvent string
+	}{
+		{
+			name:      "no changes",
+			results:   []FileResult{{Path: "a.txt"}},
+			expectNil: true,
+		},
+		{
+			name:        "changes without hook input",
+			results:     []FileResult{{Path: "a.txt", Changes: []string{changeAddedNewline}}},
+			expectEvent: "PostToolUse",
+		},
+		{
+			name:        "changes with hook event",
+			results:     []FileResult{{Path: "a.txt", Changes: []string{changeAddedNewline}}},
+			hook:        &toolinput.HookInput{HookEventName: "PostToolUse"},
+			expectEvent: "PostToolUse",
+		},
+		{
+			// Stop hooks only report to the user since Claude's turn is over
+			name:        "changes after stop",
+			results:     []FileResult{{Path: "a.txt", Changes: []string{changeAddedNewline}}},
+			hook:        &toolinput.HookInput{HookEventName: "Stop"},
+			expectEvent: "",
+		},
+		{
+			name:        "changes after subagent stop",
+			results:     []FileResult{{Path: "a.txt", Changes: []string{changeAddedNewline}}},
+			hook:        &toolinput.HookInput{HookEventName: "SubagentStop"},
+			expectEvent: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &Report{Results: tt.results}
+			resp := buildHookResponse(report, tt.hook)
+
+			if (resp == nil) != tt.expectNil {
+				t.Fatalf("buildHookResponse() = %v, expectNil %v", resp, tt.expectNil)
+			}
+			if resp == nil {
+				return
+			}
+			if !resp.SuppressOutput {
+				t.Error("SuppressOutput should be true")
+			}
+			if !strings.Contains(resp.SystemMessage, "a.txt: added final newline") {
+				t.Errorf("SystemMessage = %q", resp.SystemMessage)
+			}
+			if tt.expectEvent == "" {
+				if resp.HookSpecificOutput != nil {
+					t.Errorf("HookSpecificOutput = %+v, want none", resp.HookSpecificOutput)
+				}
+				return
+			}
+			if resp.HookSpecificOutput.HookEventName != tt.expectEvent {
+				t.Errorf("HookEventName = %v, want %v", resp.HookSpecificOutput.HookEventName, tt.expectEvent)
+			}
+			if !strings.Contains(resp.HookSpecificOutput.AdditionalContext, "- a.txt: added final newline") {
+				t.Errorf("AdditionalContext = %q", resp.HookSpecificOutput.AdditionalContext)
+			}
+		})
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer := &responseWriter{Writer: &buf}
+	logger := &mockLogger{}
+
+	writer.write(logger, nil)
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output for nil response, got %q", buf.String())
+	}
+
+	report := &Report{Results: []FileResult{{Path: "a.txt", Changes: []string{changeAddedNewline}}}}
+	writer.write(logger, buildHookResponse(report, nil))
+
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v (%q)", err, buf.String())
+	}
+	if _, ok := decoded["hookSpecificOutput"]; !ok {
+		t.Error("Expected hookSpecificOutput in response")
+	}
+}
@@ -0,0 +1,29 @@
+package processing
+
+import (
+	"fmt"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/logging"
+	"github.com/koh-sh/ccnewline/internal/toolinput"
+)
+
+// stopHookEvents are the hook events that end a turn rather than follow a tool call
+var stopHookEvents = []string{"Stop", "SubagentStop"}
+
+// readSessionPaths returns the files written during the session of a Stop or
+// SubagentStop payload, as recorded in its transcript
+func readSessionPaths(config *cli.Config, logger logging.Logger, hook *toolinput.HookInput) []string {
+	if hook == nil {
+		logger.Debug("Stop mode needs a hook payload with transcript_path")
+		return nil
+	}
+
+	paths, err := toolinput.ReadTranscript(logger, hook, newToolSelector(config).accepts)
+	if err != nil {
+		newErrorHandler().handleError(logger, hook.Transcript(), err)
+		return nil
+	}
+	logger.Debug(fmt.Sprintf("Found %d file(s) written during the session", len(paths)))
+	return paths
+}
@@ -0,0 +1,104 @@
+package processing
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/toolinput"
+)
+
+func TestReadSessionPaths(t *testing.T) {
+	tempDir := t.TempDir()
+	transcript := filepath.Join(tempDir, "session.jsonl")
+	_ = os.WriteFile(transcript, []byte(
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"/p/a.go"}}]}}`+"\n"+
+			`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Write","input":{"file_path":"/p/b.go"}}]}}`+"\n"), 0o644)
+
+	tests := []struct {
+		name     string
+		config   *cli.Config
+		hook     *toolinput.HookInput
+		expected []string
+	}{
+		{
+			name:     "no hook input",
+			config:   &cli.Config{},
+			hook:     nil,
+			expected: nil,
+		},
+		{
+			name:     "missing transcript",
+			config:   &cli.Config{},
+			hook:     &toolinput.HookInput{TranscriptPath: filepath.Join(tempDir, "missing.jsonl")},
+			expected: nil,
+		},
+		{
+			name:     "all writes",
+			config:   &cli.Config{},
+			hook:     &toolinput.HookInput{TranscriptPath: transcript},
+			expected: []string{"/p/a.go", "/p/b.go"},
+		},
+		{
+			name:     "tools list",
+			config:   &cli.Config{Tools: []string{"Write"}},
+			hook:     &toolinput.HookInput{TranscriptPath: transcript},
+			expected: []string{"/p/b.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := readSessionPaths(tt.config, &mockLogger{}, tt.hook)
+			if strings.Join(paths, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("readSessionPaths() = %v, want %v", paths, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadSessionPathsReportsTranscriptRead(t *testing.T) {
+	tempDir := t.TempDir()
+	hook := &toolinput.HookInput{
+		TranscriptPath:      filepath.Join(tempDir, "session.jsonl"),
+		AgentTranscriptPath: filepath.Join(tempDir, "agent.jsonl"),
+	}
+
+	reader, writer, _ := os.Pipe()
+	oldStderr := os.Stderr
+	os.Stderr = writer
+	readSessionPaths(&cli.Config{}, &mockLogger{}, hook)
+	os.Stderr = oldStderr
+	_ = writer.Close()
+	output, _ := io.ReadAll(reader)
+
+	if !strings.Contains(string(output), "Error processing "+hook.AgentTranscriptPath+":") {
+		t.Errorf("Error should name the subagent transcript, got %q", output)
+	}
+}
+
+func TestRunStopEvent(t *testing.T) {
+	tempDir := t.TempDir()
+	written := filepath.Join(tempDir, "written.txt")
+	untouched := filepath.Join(tempDir, "untouched.txt")
+	_ = os.WriteFile(written, []byte("content"), 0o644)
+	_ = os.WriteFile(untouched, []byte("content"), 0o644)
+
+	transcript := filepath.Join(tempDir, "session.jsonl")
+	_ = os.WriteFile(transcript, []byte(
+		`{"type":"assistant","cwd":"`+tempDir+`","message":{"content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"written.txt"}}]}}`+"\n"+
+			`{"type":"assistant","cwd":"`+tempDir+`","message":{"content":[{"type":"tool_use","id":"t2","name":"Read","input":{"file_path":"untouched.txt"}}]}}`+"\n"), 0o644)
+
+	input := `{"hook_event_name": "Stop", "cwd": "` + tempDir + `", "transcript_path": "` + transcript + `"}`
+	Run(&cli.Config{Silent: true, Event: cli.EventStop}, &mockLogger{}, strings.NewReader(input))
+
+	if content, _ := os.ReadFile(written); string(content) != "content\n" {
+		t.Errorf("Written file = %q, want newline added", content)
+	}
+	if content, _ := os.ReadFile(untouched); string(content) != "content" {
+		t.Errorf("File only read during the session should not be modified, got %q", content)
+	}
+}
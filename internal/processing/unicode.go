@@ -0,0 +1,155 @@
+package processing
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+// changeReplacedInvisible records that invisible characters were replaced or removed
+const changeReplacedInvisible = "replaced %d invisible character(s)"
+
+// invisibleReplacements maps the invisible characters that are safe to fix to their replacement
+var invisibleReplacements = map[rune]string{
+	'\u00a0': " ", // no-break space
+	'\u202f': " ", // narrow no-break space
+	'\u200b': "",  // zero width space
+	'\u2060': "",  // word joiner
+	'\ufeff': "",  // zero width no-break space, when not a byte order mark
+}
+
+// confusableCharacters are reported wherever they appear: they are invisible,
+// reorder text or pass for ASCII, and replacing them could change meaning
+var confusableCharacters = map[rune]string{
+	'\u00ad': "soft hyphen",
+	'\u037e': "Greek question mark",
+	'\u2000': "en quad",
+	'\u2001': "em quad",
+	'\u2002': "en space",
+	'\u2003': "em space",
+	'\u2004': "three-per-em space",
+	'\u2005': "four-per-em space",
+	'\u2006': "six-per-em space",
+	'\u2007': "figure space",
+	'\u2008': "punctuation space",
+	'\u2009': "thin space",
+	'\u200a': "hair space",
+	'\u200e': "left-to-right mark",
+	'\u200f': "right-to-left mark",
+	'\u2028': "line separator",
+	'\u2029': "paragraph separator",
+	'\u202a': "left-to-right embedding",
+	'\u202b': "right-to-left embedding",
+	'\u202c': "pop directional formatting",
+	'\u202d': "left-to-right override",
+	'\u202e': "right-to-left override",
+	'\u2066': "left-to-right isolate",
+	'\u2067': "right-to-left isolate",
+	'\u2068': "first strong isolate",
+	'\u2069': "pop directional isolate",
+	'\u2212': "minus sign",
+}
+
+// typographicCharacters are reported unless the policy allows typography, since
+// prose uses them on purpose while code expects their ASCII counterparts
+var typographicCharacters = map[rune]string{
+	'\u2018': "left single quotation mark",
+	'\u2019': "right single quotation mark",
+	'\u201a': "single low-9 quotation mark",
+	'\u201b': "single high-reversed-9 quotation mark",
+	'\u201c': "left double quotation mark",
+	'\u201d': "right double quotation mark",
+	'\u201e': "double low-9 quotation mark",
+	'\u201f': "double high-reversed-9 quotation mark",
+	'\u2032': "prime",
+	'\u2033': "double prime",
+	'\u2013': "en dash",
+	'\u2014': "em dash",
+	'\u2026': "horizontal ellipsis",
+}
+
+// invisibleCharacterFixer replaces no-break spaces with spaces and removes zero width characters
+type invisibleCharacterFixer struct{}
+
+// newInvisibleCharacterFixer creates an invisible character fixer, or returns nil
+// when the policy does not ask for Unicode cleanup
+func newInvisibleCharacterFixer(policy cli.Policy) *invisibleCharacterFixer {
+	if !enabled(policy.UnicodeCleanup) {
+		return nil
+	}
+	return &invisibleCharacterFixer{}
+}
+
+// fix replaces the invisible characters, keeping a leading byte order mark
+func (icf *invisibleCharacterFixer) fix(content []byte) ([]byte, string) {
+	var fixed bytes.Buffer
+	replaced := 0
+	if bytes.HasPrefix(content, utf8BOM) {
+		fixed.Write(utf8BOM)
+		content = content[len(utf8BOM):]
+	}
+
+	for len(content) > 0 {
+		r, size := utf8.DecodeRune(content)
+		if replacement, ok := invisibleReplacements[r]; ok {
+			fixed.WriteString(replacement)
+			replaced++
+		} else {
+			fixed.Write(content[:size])
+		}
+		content = content[size:]
+	}
+
+	if replaced == 0 {
+		return nil, ""
+	}
+	return fixed.Bytes(), fmt.Sprintf(changeReplacedInvisible, replaced)
+}
+
+// confusableScanner finds the characters Unicode cleanup reports rather than fixes
+type confusableScanner struct {
+	// allowTypographic leaves typographic quotes, dashes and ellipses unreported
+	allowTypographic bool
+}
+
+// newConfusableScanner creates a confusable scanner for the policy, or returns nil
+// when the policy does not ask for Unicode cleanup
+func newConfusableScanner(policy cli.Policy) *confusableScanner {
+	if !enabled(policy.UnicodeCleanup) {
+		return nil
+	}
+	return &confusableScanner{allowTypographic: enabled(policy.AllowTypographic)}
+}
+
+// name returns the name of a character to report, or "" when it is not reported
+func (cs *confusableScanner) name(r rune) string {
+	if name, ok := confusableCharacters[r]; ok {
+		return name
+	}
+	if cs.allowTypographic {
+		return ""
+	}
+	return typographicCharacters[r]
+}
+
+// scan returns a finding for each reported character, formatted as
+// "line:column: U+XXXX name" with 1-based positions counted in characters
+func (cs *confusableScanner) scan(content []byte) []string {
+	var findings []string
+	line, column := 1, 0
+	for len(content) > 0 {
+		r, size := utf8.DecodeRune(content)
+		content = content[size:]
+		column++
+		if r == '\n' {
+			line, column = line+1, 0
+			continue
+		}
+		if name := cs.name(r); name != "" {
+			findings = append(findings, fmt.Sprintf("%d:%d: %U %s", line, column, r, name))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,160 @@
+package processing
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+func TestNewInvisibleCharacterFixer(t *testing.T) {
+	on, off := true, false
+
+	if fixer := newInvisibleCharacterFixer(defaultPolicy); fixer != nil {
+		t.Errorf("newInvisibleCharacterFixer() = %v for the default policy, want nil", fixer)
+	}
+	if fixer := newInvisibleCharacterFixer(cli.Policy{UnicodeCleanup: &off}); fixer != nil {
+		t.Errorf("newInvisibleCharacterFixer() = %v with cleanup off, want nil", fixer)
+	}
+	if fixer := newInvisibleCharacterFixer(cli.Policy{UnicodeCleanup: &on}); fixer == nil {
+		t.Error("newInvisibleCharacterFixer() = nil with cleanup on")
+	}
+}
+
+func TestInvisibleCharacterFixerFix(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		expected     string
+		expectChange string
+	}{
+		{name: "clean", content: "x := 1\n", expectChange: ""},
+		{name: "no-break spaces", content: "x\u00a0:=\u202f1\n", expected: "x := 1\n", expectChange: "replaced 2 invisible character(s)"},
+		{name: "zero width characters", content: "fo\u200bo\u2060\ufeff\n", expected: "foo\n", expectChange: "replaced 3 invisible character(s)"},
+		{name: "leading byte order mark kept", content: "\ufeffa\u00a0b\n", expected: "\ufeffa b\n", expectChange: "replaced 1 invisible character(s)"},
+		{name: "only a byte order mark", content: "\ufeffa\n", expectChange: ""},
+		{name: "confusables left alone", content: "“quoted” — \u202e\n", expectChange: ""},
+		{name: "invalid UTF-8 kept", content: "a\xff\u00a0\n", expected: "a\xff \n", expectChange: "replaced 1 invisible character(s)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, change := (&invisibleCharacterFixer{}).fix([]byte(tt.content))
+			if change != tt.expectChange {
+				t.Errorf("fix() change = %q, want %q", change, tt.expectChange)
+			}
+			if tt.expectChange != "" && string(fixed) != tt.expected {
+				t.Errorf("fix() = %q, want %q", fixed, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfusableScannerScan(t *testing.T) {
+	tests := []struct {
+		name             string
+		allowTypographic bool
+		content          string
+		expected         []string
+	}{
+		{name: "clean", content: "x := \"a\"\n", expected: nil},
+		{
+			name:     "smart quotes",
+			content:  "x := “a”\n",
+			expected: []string{"1:6: U+201C left double quotation mark", "1:8: U+201D right double quotation mark"},
+		},
+		{name: "typography allowed", allowTypographic: true, content: "It’s done — really…\n", expected: nil},
+		{
+			name:             "bidi control reported in prose",
+			allowTypographic: true,
+			content:          "ok\n\tif a\u202e {\n",
+			expected:         []string{"2:6: U+202E right-to-left override"},
+		},
+		{
+			name:     "columns counted in characters",
+			content:  "été−x\n",
+			expected: []string{"1:4: U+2212 minus sign"},
+		},
+		{name: "line and paragraph separators", content: "a\u2028b\n", expected: []string{"1:2: U+2028 line separator"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := &confusableScanner{allowTypographic: tt.allowTypographic}
+			if result := scanner.scan([]byte(tt.content)); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("scan() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAddNewlineIfNeededCleansUpUnicode(t *testing.T) {
+	on := true
+	options := processOptions{
+		policies: newPolicyResolver(cli.PolicySet{
+			Policy: cli.Policy{UnicodeCleanup: &on},
+			Rules:  []cli.Rule{{Pattern: "*.md", Policy: cli.Policy{AllowTypographic: &on}}},
+		}),
+	}
+	dir := t.TempDir()
+	goFile := filepath.Join(dir, "main.go")
+	mdFile := filepath.Join(dir, "README.md")
+	_ = os.WriteFile(goFile, []byte("x\u00a0:= “a”\u200b\n"), 0o644)
+	_ = os.WriteFile(mdFile, []byte("It’s\u00a0done\n"), 0o644)
+
+	result := FileResult{Path: goFile}
+	if err := addNewlineIfNeeded(&mockLogger{}, goFile, options, &result); err != nil {
+		t.Fatalf("addNewlineIfNeeded() error = %v", err)
+	}
+	if content, _ := os.ReadFile(goFile); string(content) != "x := “a”\n" {
+		t.Errorf("File content = %q", content)
+	}
+	if !reflect.DeepEqual(result.Changes, []string{"replaced 2 invisible character(s)"}) {
+		t.Errorf("Changes = %v", result.Changes)
+	}
+	expectedFindings := []string{"1:6: U+201C left double quotation mark", "1:8: U+201D right double quotation mark"}
+	if !reflect.DeepEqual(result.Findings, expectedFindings) {
+		t.Errorf("Findings = %v, want %v", result.Findings, expectedFindings)
+	}
+
+	result = FileResult{Path: mdFile}
+	if err := addNewlineIfNeeded(&mockLogger{}, mdFile, options, &result); err != nil {
+		t.Fatalf("addNewlineIfNeeded() error = %v", err)
+	}
+	if content, _ := os.ReadFile(mdFile); string(content) != "It’s done\n" || result.Findings != nil {
+		t.Errorf("Prose should keep its quotes unreported, got %q, %v", content, result.Findings)
+	}
+}
+
+func TestRunReportsConfusables(t *testing.T) {
+	on := true
+	policies := cli.PolicySet{Policy: cli.Policy{UnicodeCleanup: &on}}
+	filePath := filepath.Join(t.TempDir(), "main.go")
+	_ = os.WriteFile(filePath, []byte("x := \u201ca\u201d\n"), 0o644)
+	input := `{"tool_name": "Write", "tool_input": {"file_path": "` + filePath + `"}}`
+
+	logger := &mockLogger{}
+	if code := Run(&cli.Config{Policies: policies}, logger, strings.NewReader(input)); code != exitOK {
+		t.Errorf("Run() = %v, want %v", code, exitOK)
+	}
+	expected := []string{filePath + ":1:6: U+201C left double quotation mark", filePath + ":1:8: U+201D right double quotation mark"}
+	if !reflect.DeepEqual(logger.infoMessages, expected) {
+		t.Errorf("Info output = %v, want %v", logger.infoMessages, expected)
+	}
+
+	enforceConfig := &cli.Config{Policies: policies, Enforce: true}
+	report := ProcessFiles(&mockLogger{}, []string{filePath}, newFileFilter(enforceConfig), newProcessOptions(enforceConfig))
+	var buf bytes.Buffer
+	e := newEnforcer()
+	e.Writer = &buf
+	if code := e.enforce(&mockLogger{}, report, nil); code != exitBlocking {
+		t.Errorf("enforce() = %v, want %v", code, exitBlocking)
+	}
+	if !strings.Contains(buf.String(), "- "+expected[0]+"\n") {
+		t.Errorf("Enforce output = %q, want it to list %q", buf.String(), expected[0])
+	}
+}
@@ -0,0 +1,114 @@
+package processing
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// changeTrimmedWhitespace records that trailing whitespace was removed
+const changeTrimmedWhitespace = "trimmed trailing whitespace on %d line(s)"
+
+// markdownExtensions are the extensions of Markdown files, in which two or more
+// trailing spaces mark a hard line break
+var markdownExtensions = []string{".md", ".markdown", ".mdx"}
+
+// patchExtensions are the extensions of diff and patch files, in which trailing
+// whitespace is part of the lines being changed
+var patchExtensions = []string{".diff", ".patch"}
+
+// hasExtension reports whether the file has one of the extensions, ignoring case
+func hasExtension(filePath string, extensions []string) bool {
+	return slices.Contains(extensions, strings.ToLower(filepath.Ext(filePath)))
+}
+
+// trailingWhitespaceFixer removes spaces and tabs at the end of lines
+type trailingWhitespaceFixer struct {
+	// keepHardBreaks keeps two or more trailing spaces, the Markdown hard line break
+	keepHardBreaks bool
+	// tracker follows the file's string literals; nil when they are not tracked
+	tracker literalTracker
+}
+
+// newTrailingWhitespaceFixer creates a trailing whitespace fixer suited to the file,
+// or returns nil for files whose trailing whitespace must not be touched
+func newTrailingWhitespaceFixer(filePath string) *trailingWhitespaceFixer {
+	if hasExtension(filePath, patchExtensions) {
+		return nil
+	}
+	return &trailingWhitespaceFixer{
+		keepHardBreaks: hasExtension(filePath, markdownExtensions),
+		tracker:        newLiteralTracker(filePath),
+	}
+}
+
+// fix trims each line outside string literals, leaving its line ending in place
+func (twf *trailingWhitespaceFixer) fix(content []byte) ([]byte, string) {
+	var lines [][]byte
+	for len(content) > 0 {
+		end := bytes.IndexByte(content, newlineByte)
+		line, rest := content, []byte(nil)
+		if end >= 0 {
+			line, rest = content[:end+1], content[end+1:]
+		}
+		lines = append(lines, line)
+		content = rest
+	}
+
+	var fixed bytes.Buffer
+	trimmed := 0
+	inLiteral := twf.literalLines(lines)
+	for i, line := range lines {
+		if inLiteral[i] {
+			fixed.Write(line)
+			continue
+		}
+		body := bytes.TrimRight(line, "\r\n")
+		ending := line[len(body):]
+
+		kept := twf.trimLine(body)
+		if len(kept) != len(body) {
+			trimmed++
+		}
+		fixed.Write(kept)
+		fixed.Write(ending)
+	}
+
+	if trimmed == 0 {
+		return nil, ""
+	}
+	return fixed.Bytes(), fmt.Sprintf(changeTrimmedWhitespace, trimmed)
+}
+
+// literalLines reports for each line whether it starts or ends inside a string literal,
+// where trailing whitespace may be part of the string
+func (twf *trailingWhitespaceFixer) literalLines(lines [][]byte) []bool {
+	inLiteral := make([]bool, len(lines))
+	if twf.tracker == nil {
+		return inLiteral
+	}
+
+	// A line ends inside a literal when the line after it starts inside one
+	startsInside := make([]bool, len(lines)+1)
+	for i, line := range lines {
+		startsInside[i] = twf.tracker.next(string(line))
+	}
+	startsInside[len(lines)] = twf.tracker.next("")
+	for i := range lines {
+		inLiteral[i] = startsInside[i] || startsInside[i+1]
+	}
+	return inLiteral
+}
+
+// trimLine removes the trailing whitespace of a line without its line ending
+func (twf *trailingWhitespaceFixer) trimLine(line []byte) []byte {
+	kept := bytes.TrimRight(line, " \t")
+	trailing := line[len(kept):]
+	// A hard line break is two or more spaces after text
+	if twf.keepHardBreaks && len(kept) > 0 && len(trailing) >= 2 && len(bytes.Trim(trailing, " ")) == 0 {
+		return line
+	}
+	return kept
+}
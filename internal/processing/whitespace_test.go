@@ -0,0 +1,124 @@
+package processing
+
+import (
+	"testing"
+)
+
+func TestNewTrailingWhitespaceFixer(t *testing.T) {
+	tests := []struct {
+		name           string
+		filePath       string
+		expectNil      bool
+		expectKeepHard bool
+	}{
+		{name: "go file", filePath: "/a/main.go"},
+		{name: "markdown", filePath: "/a/README.md", expectKeepHard: true},
+		{name: "markdown upper case", filePath: "/a/NOTES.MARKDOWN", expectKeepHard: true},
+		{name: "diff", filePath: "/a/change.diff", expectNil: true},
+		{name: "patch", filePath: "/a/0001-fix.patch", expectNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixer := newTrailingWhitespaceFixer(tt.filePath)
+			if (fixer == nil) != tt.expectNil {
+				t.Fatalf("newTrailingWhitespaceFixer(%q) = %v, expectNil %v", tt.filePath, fixer, tt.expectNil)
+			}
+			if fixer != nil && fixer.keepHardBreaks != tt.expectKeepHard {
+				t.Errorf("keepHardBreaks = %v, want %v", fixer.keepHardBreaks, tt.expectKeepHard)
+			}
+		})
+	}
+}
+
+func TestTrailingWhitespaceFixerFix(t *testing.T) {
+	tests := []struct {
+		name           string
+		keepHardBreaks bool
+		content        string
+		expected       string
+		expectChange   string
+	}{
+		{name: "clean", content: "a\nb\n", expectChange: ""},
+		{name: "spaces and tabs", content: "a  \nb\t\nc\n", expected: "a\nb\nc\n", expectChange: "trimmed trailing whitespace on 2 line(s)"},
+		{name: "CRLF kept", content: "a \r\nb\r\n", expected: "a\r\nb\r\n", expectChange: "trimmed trailing whitespace on 1 line(s)"},
+		{name: "last line without newline", content: "a\nb  ", expected: "a\nb", expectChange: "trimmed trailing whitespace on 1 line(s)"},
+		{name: "whitespace-only line", content: "a\n   \nb\n", expected: "a\n\nb\n", expectChange: "trimmed trailing whitespace on 1 line(s)"},
+		{name: "leading whitespace kept", content: "\tx = 1 \n", expected: "\tx = 1\n", expectChange: "trimmed trailing whitespace on 1 line(s)"},
+		{name: "hard break kept in markdown", keepHardBreaks: true, content: "line one  \nline two\n", expectChange: ""},
+		{name: "single space trimmed in markdown", keepHardBreaks: true, content: "line one \nline two\n", expected: "line one\nline two\n", expectChange: "trimmed trailing whitespace on 1 line(s)"},
+		{name: "tabs trimmed in markdown", keepHardBreaks: true, content: "line one \t\n", expected: "line one\n", expectChange: "trimmed trailing whitespace on 1 line(s)"},
+		{name: "blank line trimmed in markdown", keepHardBreaks: true, content: "a\n    \nb\n", expected: "a\n\nb\n", expectChange: "trimmed trailing whitespace on 1 line(s)"},
+		{name: "hard break not kept outside markdown", content: "line one  \n", expected: "line one\n", expectChange: "trimmed trailing whitespace on 1 line(s)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixer := &trailingWhitespaceFixer{keepHardBreaks: tt.keepHardBreaks}
+			fixed, change := fixer.fix([]byte(tt.content))
+			if change != tt.expectChange {
+				t.Errorf("fix() change = %q, want %q", change, tt.expectChange)
+			}
+			if change != "" && string(fixed) != tt.expected {
+				t.Errorf("fix() = %q, want %q", fixed, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTrailingWhitespaceFixerKeepsLiterals(t *testing.T) {
+	tests := []struct {
+		name         string
+		filePath     string
+		content      string
+		expected     string
+		expectChange string
+	}{
+		{
+			name:         "go raw string",
+			filePath:     "/a/main.go",
+			content:      "x := `a  \n  b \n`  \ny := 1 \n",
+			expected:     "x := `a  \n  b \n`  \ny := 1\n",
+			expectChange: "trimmed trailing whitespace on 1 line(s)",
+		},
+		{
+			name:         "python triple-quoted string",
+			filePath:     "/a/app.py",
+			content:      "s = \"\"\"\nline  \n\"\"\"\nx = 1 \n",
+			expected:     "s = \"\"\"\nline  \n\"\"\"\nx = 1\n",
+			expectChange: "trimmed trailing whitespace on 1 line(s)",
+		},
+		{
+			name:         "shell heredoc",
+			filePath:     "/a/run.sh",
+			content:      "echo a \ncat <<EOF\nbody  \nEOF\n",
+			expected:     "echo a\ncat <<EOF\nbody  \nEOF\n",
+			expectChange: "trimmed trailing whitespace on 1 line(s)",
+		},
+		{
+			name:         "unterminated literal at the end",
+			filePath:     "/a/main.go",
+			content:      "x := `a \nb ",
+			expectChange: "",
+		},
+		{
+			name:         "untracked language",
+			filePath:     "/a/notes.txt",
+			content:      "x := `a \n`\n",
+			expected:     "x := `a\n`\n",
+			expectChange: "trimmed trailing whitespace on 1 line(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixed, change := newTrailingWhitespaceFixer(tt.filePath).fix([]byte(tt.content))
+			if change != tt.expectChange {
+				t.Errorf("fix() change = %q, want %q", change, tt.expectChange)
+			}
+			if tt.expectChange != "" && string(fixed) != tt.expected {
+				t.Errorf("fix() = %q, want %q", fixed, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package toolinput
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// errNoToolInput is returned when a hook payload carries no tool_input object
+var errNoToolInput = errors.New("hook input has no tool_input")
+
+// HookInput is the JSON payload Claude Code sends to hook commands on stdin
+type HookInput struct {
+	// SessionID identifies the Claude Code session that fired the hook
+	SessionID string `json:"session_id"`
+	// TranscriptPath is the path of the session's JSONL transcript
+	TranscriptPath string `json:"transcript_path"`
+	// AgentTranscriptPath is the path of a subagent's own transcript, present for SubagentStop events
+	AgentTranscriptPath string `json:"agent_transcript_path,omitempty"`
+	// Cwd is the working directory of the session when the hook fired
+	Cwd string `json:"cwd"`
+	// HookEventName is the hook event, such as PostToolUse
+	HookEventName string `json:"hook_event_name"`
+	// PermissionMode is the session's permission mode, such as acceptEdits
+	PermissionMode string `json:"permission_mode"`
+	// ToolName is the name of the tool that triggered the hook
+	ToolName string `json:"tool_name"`
+	// ToolInput holds the raw tool arguments, decoded on demand by tool
+	ToolInput json.RawMessage `json:"tool_input,omitempty"`
+	// ToolResponse holds the raw tool result, present for PostToolUse events
+	ToolResponse json.RawMessage `json:"tool_response,omitempty"`
+	// StopHookActive reports that Claude is already continuing because a Stop hook blocked
+	StopHookActive bool `json:"stop_hook_active,omitempty"`
+}
+
+// WriteInput holds the arguments of the Write tool
+type WriteInput struct {
+	FilePath string `json:"file_path"`
+	Content  string `json:"content"`
+}
+
+// EditInput holds the arguments of the Edit tool
+type EditInput struct {
+	FilePath   string `json:"file_path"`
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
+}
+
+// EditOperation is a single replacement within a MultiEdit call
+type EditOperation struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all,omitempty"`
+}
+
+// MultiEditInput holds the arguments of the MultiEdit tool
+type MultiEditInput struct {
+	FilePath string          `json:"file_path"`
+	Edits    []EditOperation `json:"edits"`
+}
+
+// NotebookEditInput holds the arguments of the NotebookEdit tool
+type NotebookEditInput struct {
+	NotebookPath string `json:"notebook_path"`
+	CellID       string `json:"cell_id,omitempty"`
+	NewSource    string `json:"new_source"`
+	CellType     string `json:"cell_type,omitempty"`
+	EditMode     string `json:"edit_mode,omitempty"`
+}
+
+// ToolResponse holds the fields of a tool result that ccnewline cares about
+type ToolResponse struct {
+	// FilePath is the file the tool actually wrote
+	FilePath string `json:"filePath"`
+	// Type is "create" or "update" for the Write tool
+	Type string `json:"type"`
+	// Success reports whether the tool call succeeded, when the tool says so
+	Success *bool `json:"success"`
+	// Error describes why the tool call failed, when it did
+	Error string `json:"error"`
+}
+
+// Failed reports whether the response marks the tool call as failed
+func (tr *ToolResponse) Failed() bool {
+	return (tr.Success != nil && !*tr.Success) || tr.Error != ""
+}
+
+// Transcript returns the path of the transcript that records the payload's session:
+// the subagent's own transcript for SubagentStop events, the session transcript otherwise
+func (h *HookInput) Transcript() string {
+	if h.AgentTranscriptPath != "" {
+		return h.AgentTranscriptPath
+	}
+	return h.TranscriptPath
+}
+
+// DecodeToolInput decodes the tool_input object into v
+func (h *HookInput) DecodeToolInput(v any) error {
+	if len(h.ToolInput) == 0 {
+		return errNoToolInput
+	}
+	return json.Unmarshal(h.ToolInput, v)
+}
+
+// WriteInput decodes tool_input as Write tool arguments
+func (h *HookInput) WriteInput() (*WriteInput, error) {
+	var in WriteInput
+	if err := h.DecodeToolInput(&in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// EditInput decodes tool_input as Edit tool arguments
+func (h *HookInput) EditInput() (*EditInput, error) {
+	var in EditInput
+	if err := h.DecodeToolInput(&in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// MultiEditInput decodes tool_input as MultiEdit tool arguments
+func (h *HookInput) MultiEditInput() (*MultiEditInput, error) {
+	var in MultiEditInput
+	if err := h.DecodeToolInput(&in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// NotebookEditInput decodes tool_input as NotebookEdit tool arguments
+func (h *HookInput) NotebookEditInput() (*NotebookEditInput, error) {
+	var in NotebookEditInput
+	if err := h.DecodeToolInput(&in); err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+// Response decodes tool_response, returning nil when the payload has none
+// or when the tool responded with something other than an object
+func (h *HookInput) Response() *ToolResponse {
+	if len(h.ToolResponse) == 0 {
+		return nil
+	}
+	var resp ToolResponse
+	if err := json.Unmarshal(h.ToolResponse, &resp); err != nil {
+		return nil
+	}
+	return &resp
+}
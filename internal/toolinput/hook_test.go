@@ -0,0 +1,308 @@
+package toolinput
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHookInput(t *testing.T) {
+	extractor := newPathExtractor()
+
+	input := `{
+		"session_id": "abc123",
+		"transcript_path": "/home/user/.claude/projects/p/abc123.jsonl",
+		"cwd": "/home/user/project",
+		"hook_event_name": "PostToolUse",
+		"tool_name": "Write",
+		"tool_input": {"file_path": "/home/user/project/main.go", "content": "package main"},
+		"tool_response": {"filePath": "/home/user/project/main.go", "type": "create", "success": true}
+	}`
+
+	hook, paths, err := extractor.parseHookInput(input)
+	if err != nil {
+		t.Fatalf("parseHookInput() error = %v", err)
+	}
+
+	if hook.SessionID != "abc123" {
+		t.Errorf("SessionID = %v, want %v", hook.SessionID, "abc123")
+	}
+	if hook.TranscriptPath != "/home/user/.claude/projects/p/abc123.jsonl" {
+		t.Errorf("TranscriptPath = %v", hook.TranscriptPath)
+	}
+	if hook.Cwd != "/home/user/project" {
+		t.Errorf("Cwd = %v, want %v", hook.Cwd, "/home/user/project")
+	}
+	if hook.HookEventName != "PostToolUse" {
+		t.Errorf("HookEventName = %v, want %v", hook.HookEventName, "PostToolUse")
+	}
+	if hook.ToolName != "Write" {
+		t.Errorf("ToolName = %v, want %v", hook.ToolName, "Write")
+	}
+	if len(paths) != 1 || paths[0] != "/home/user/project/main.go" {
+		t.Errorf("paths = %v, want [/home/user/project/main.go]", paths)
+	}
+}
+
+func TestHookInputWriteInput(t *testing.T) {
+	hook := &HookInput{ToolInput: []byte(`{"file_path": "/test/file.go", "content": "package main"}`)}
+
+	in, err := hook.WriteInput()
+	if err != nil {
+		t.Fatalf("WriteInput() error = %v", err)
+	}
+	if in.FilePath != "/test/file.go" {
+		t.Errorf("FilePath = %v, want %v", in.FilePath, "/test/file.go")
+	}
+	if in.Content != "package main" {
+		t.Errorf("Content = %q, want %q", in.Content, "package main")
+	}
+}
+
+func TestHookInputEditInput(t *testing.T) {
+	hook := &HookInput{ToolInput: []byte(`{"file_path": "/test/file.go", "old_string": "a", "new_string": "b", "replace_all": true}`)}
+
+	in, err := hook.EditInput()
+	if err != nil {
+		t.Fatalf("EditInput() error = %v", err)
+	}
+	if in.FilePath != "/test/file.go" || in.OldString != "a" || in.NewString != "b" || !in.ReplaceAll {
+		t.Errorf("EditInput() = %+v", in)
+	}
+}
+
+func TestHookInputMultiEditInput(t *testing.T) {
+	hook := &HookInput{ToolInput: []byte(`{"file_path": "/test/file.go", "edits": [
+		{"old_string": "a", "new_string": "b"},
+		{"old_string": "c", "new_string": "d", "replace_all": true}
+	]}`)}
+
+	in, err := hook.MultiEditInput()
+	if err != nil {
+		t.Fatalf("MultiEditInput() error = %v", err)
+	}
+	if in.FilePath != "/test/file.go" {
+		t.Errorf("FilePath = %v, want %v", in.FilePath, "/test/file.go")
+	}
+	if len(in.Edits) != 2 {
+		t.Fatalf("Edits length = %v, want 2", len(in.Edits))
+	}
+	if in.Edits[1].OldString != "c" || in.Edits[1].NewString != "d" || !in.Edits[1].ReplaceAll {
+		t.Errorf("Edits[1] = %+v", in.Edits[1])
+	}
+}
+
+func TestHookInputNotebookEditInput(t *testing.T) {
+	hook := &HookInput{ToolInput: []byte(`{"notebook_path": "/test/nb.ipynb", "cell_id": "c1", "new_source": "x = 1", "edit_mode": "replace"}`)}
+
+	in, err := hook.NotebookEditInput()
+	if err != nil {
+		t.Fatalf("NotebookEditInput() error = %v", err)
+	}
+	if in.NotebookPath != "/test/nb.ipynb" || in.CellID != "c1" || in.NewSource != "x = 1" || in.EditMode != "replace" {
+		t.Errorf("NotebookEditInput() = %+v", in)
+	}
+}
+
+func TestHookInputDecodeToolInputMissing(t *testing.T) {
+	hook := &HookInput{}
+	if _, err := hook.WriteInput(); err == nil {
+		t.Error("Expected error for missing tool_input")
+	}
+}
+
+func TestHookInputTranscript(t *testing.T) {
+	tests := []struct {
+		name     string
+		hook     HookInput
+		expected string
+	}{
+		{name: "stop", hook: HookInput{TranscriptPath: "/t/session.jsonl"}, expected: "/t/session.jsonl"},
+		{
+			name:     "subagent stop",
+			hook:     HookInput{TranscriptPath: "/t/session.jsonl", AgentTranscriptPath: "/t/agent.jsonl"},
+			expected: "/t/agent.jsonl",
+		},
+		{name: "none", hook: HookInput{}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.hook.Transcript(); result != tt.expected {
+				t.Errorf("Transcript() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHookInputResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		response     string
+		expectNil    bool
+		expectPath   string
+		expectType   string
+		expectFailed bool
+	}{
+		{
+			name:      "no response",
+			response:  "",
+			expectNil: true,
+		},
+		{
+			name:       "write response",
+			response:   `{"filePath": "/test/file.go", "type": "update"}`,
+			expectPath: "/test/file.go",
+			expectType: "update",
+		},
+		{
+			name:         "failed response",
+			response:     `{"filePath": "/test/file.go", "success": false}`,
+			expectPath:   "/test/file.go",
+			expectFailed: true,
+		},
+		{
+			name:      "string response",
+			response:  `"Error: file not found"`,
+			expectNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := &HookInput{ToolResponse: []byte(tt.response)}
+			resp := hook.Response()
+
+			if (resp == nil) != tt.expectNil {
+				t.Fatalf("Response() = %v, expectNil %v", resp, tt.expectNil)
+			}
+			if resp == nil {
+				return
+			}
+			if resp.FilePath != tt.expectPath {
+				t.Errorf("FilePath = %v, want %v", resp.FilePath, tt.expectPath)
+			}
+			if resp.Type != tt.expectType {
+				t.Errorf("Type = %v, want %v", resp.Type, tt.expectType)
+			}
+			failed := resp.Success != nil && !*resp.Success
+			if failed != tt.expectFailed {
+				t.Errorf("failed = %v, want %v", failed, tt.expectFailed)
+			}
+		})
+	}
+}
+
+func TestReadToolInputReturnsHook(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		expectHook bool
+		expectTool string
+	}{
+		{
+			name:       "JSON input",
+			input:      `{"hook_event_name": "PostToolUse", "tool_name": "Edit", "tool_input": {"file_path": "/test/file.txt"}}`,
+			expectHook: true,
+			expectTool: "Edit",
+		},
+		{
+			name:       "plain text input",
+			input:      "/test/file.txt",
+			expectHook: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &mockLogger{}
+			_, hook := ReadToolInput(logger, strings.NewReader(tt.input))
+
+			if (hook != nil) != tt.expectHook {
+				t.Fatalf("ReadToolInput() hook = %v, expectHook %v", hook, tt.expectHook)
+			}
+			if hook != nil && hook.ToolName != tt.expectTool {
+				t.Errorf("ToolName = %v, want %v", hook.ToolName, tt.expectTool)
+			}
+		})
+	}
+}
+
+func TestApplyToolResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		paths    []string
+		expected []string
+	}{
+		{
+			name:     "no response",
+			response: "",
+			paths:    []string{"/test/file.txt"},
+			expected: []string{"/test/file.txt"},
+		},
+		{
+			name:     "successful response without file path",
+			response: `{"success": true}`,
+			paths:    []string{"/test/file.txt"},
+			expected: []string{"/test/file.txt"},
+		},
+		{
+			name:     "response file path preferred",
+			response: `{"filePath": "/real/file.txt", "type": "update"}`,
+			paths:    []string{"file.txt"},
+			expected: []string{"/real/file.txt"},
+		},
+		{
+			name:     "failed response",
+			response: `{"filePath": "/test/file.txt", "success": false}`,
+			paths:    []string{"/test/file.txt"},
+			expected: nil,
+		},
+		{
+			name:     "error response",
+			response: `{"error": "String to replace not found in file"}`,
+			paths:    []string{"/test/file.txt"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &mockLogger{}
+			hook := &HookInput{ToolResponse: []byte(tt.response)}
+			result := applyToolResponse(logger, hook, tt.paths)
+
+			if len(result) != len(tt.expected) {
+				t.Fatalf("applyToolResponse() = %v, want %v", result, tt.expected)
+			}
+			for i, path := range result {
+				if path != tt.expected[i] {
+					t.Errorf("applyToolResponse()[%d] = %v, want %v", i, path, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReadToolInputSkipsFailedToolCall(t *testing.T) {
+	logger := &mockLogger{}
+	input := `{"tool_name": "Edit", "tool_input": {"file_path": "/test/file.txt"}, "tool_response": {"success": false}}`
+
+	paths, hook := ReadToolInput(logger, strings.NewReader(input))
+	if hook == nil {
+		t.Fatal("Expected hook input")
+	}
+	if len(paths) != 0 {
+		t.Errorf("ReadToolInput() = %v, want no paths", paths)
+	}
+
+	found := false
+	for _, msg := range logger.debugMessages {
+		if strings.Contains(msg, "tool call failed") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected debug message explaining the skip")
+	}
+}
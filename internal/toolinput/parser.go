@@ -11,6 +11,28 @@ import (
 	"github.com/koh-sh/ccnewline/internal/logging"
 )
 
+// Payload is a unit of input: a hook payload with the file paths it names,
+// or the file paths of plain text input, in which case Hook is nil
+type Payload struct {
+	Hook  *HookInput
+	Paths []string
+}
+
+// Options controls how input is read
+type Options struct {
+	// MaxSize caps the input size in bytes; zero means no limit
+	MaxSize int64
+	// Strict accepts only hook JSON payloads instead of falling back to plain text paths
+	Strict bool
+	// PathsOnly reads the input as a list of file paths without looking for JSON
+	PathsOnly bool
+	// NullSeparated splits the path list on NUL bytes instead of newlines
+	NullSeparated bool
+	// DiscardToolInput releases the raw tool_input of hook payloads once their paths
+	// are read, for callers that do not decode the tool arguments
+	DiscardToolInput bool
+}
+
 // pathExtractor extracts file paths from various input formats
 type pathExtractor struct{}
 
@@ -19,38 +41,29 @@ func newPathExtractor() *pathExtractor {
 	return &pathExtractor{}
 }
 
-// parse extracts file paths from input text (JSON or plain text)
-func (pe *pathExtractor) parse(inputText string) []string {
-	paths, err := pe.parseJSON(inputText)
-	if err == nil {
-		return paths
-	}
-	return pe.parsePlainText(inputText)
-}
-
-// isJSON checks if the input text is valid JSON
-func (pe *pathExtractor) isJSON(inputText string) bool {
-	var jsonObj map[string]any
-	return json.Unmarshal([]byte(inputText), &jsonObj) == nil
-}
-
 // parseJSON extracts file paths from JSON input
 func (pe *pathExtractor) parseJSON(inputText string) ([]string, error) {
-	var jsonObj map[string]any
-	if err := json.Unmarshal([]byte(inputText), &jsonObj); err != nil {
-		return nil, err
+	_, paths, err := pe.parseHookInput(inputText)
+	return paths, err
+}
+
+// parseHookInput decodes a hook payload and extracts file paths from its tool_input
+func (pe *pathExtractor) parseHookInput(inputText string) (*HookInput, []string, error) {
+	var hook HookInput
+	if err := json.Unmarshal([]byte(inputText), &hook); err != nil {
+		return nil, nil, err
 	}
 
-	var paths []string
+	return &hook, pe.pathsFromHook(&hook), nil
+}
 
-	// Extract from tool_input if present
-	if toolInput, exists := jsonObj["tool_input"]; exists {
-		if toolInputMap, ok := toolInput.(map[string]any); ok {
-			paths = append(paths, pe.extractPathsFromToolInput(toolInputMap)...)
-		}
+// pathsFromHook extracts file paths from the hook's tool_input, if present and an object
+func (pe *pathExtractor) pathsFromHook(hook *HookInput) []string {
+	var fields toolInputPaths
+	if hook.DecodeToolInput(&fields) != nil {
+		return nil
 	}
-
-	return paths, nil
+	return pe.extractPathsFromToolInput(fields.asMap())
 }
 
 // extractPathsFromToolInput extracts paths from tool_input object
@@ -58,7 +71,7 @@ func (pe *pathExtractor) extractPathsFromToolInput(toolInput map[string]any) []s
 	var paths []string
 
 	// Check for single file path fields
-	for _, field := range []string{"path", "file_path"} {
+	for _, field := range []string{"path", "file_path", "notebook_path"} {
 		if value, exists := toolInput[field]; exists {
 			if strValue, ok := value.(string); ok && strValue != "" {
 				paths = append(paths, strValue)
@@ -117,26 +130,104 @@ func (ic *inputChecker) checkAvailability(logger logging.Logger, input io.Reader
 
 // inputReader reads and processes input
 type inputReader struct {
-	pathParser   *pathExtractor
-	inputChecker *inputChecker
+	pathParser    *pathExtractor
+	inputChecker  *inputChecker
+	streamDecoder *streamDecoder
+	options       Options
 }
 
-// newInputReader creates a new input reader
+// newInputReader creates a new input reader that accepts JSON or plain text without a size limit
 func newInputReader() *inputReader {
 	return &inputReader{
-		pathParser:   newPathExtractor(),
-		inputChecker: newInputChecker(),
+		pathParser:    newPathExtractor(),
+		inputChecker:  newInputChecker(),
+		streamDecoder: newStreamDecoder(),
 	}
 }
 
-// readPaths reads file paths from input
-func (ir *inputReader) readPaths(logger logging.Logger, input io.Reader) []string {
+// readPayloads reads the payloads in input, resolving each payload's paths against its own cwd.
+// Input starting with '{' is decoded as a stream of JSON payloads; anything else is read
+// as plain text paths unless the reader is strict. Payloads read before an error are
+// returned along with it.
+func (ir *inputReader) readPayloads(logger logging.Logger, input io.Reader) ([]Payload, error) {
 	if !ir.inputChecker.checkAvailability(logger, input) {
+		return nil, nil
+	}
+
+	limited := newLimitedReader(input, ir.options.MaxSize)
+	reader := bufio.NewReaderSize(limited, jsonPeekSize)
+
+	var payloads []Payload
+	var err error
+	if ir.options.PathsOnly {
+		payloads = ir.readPathList(logger, reader)
+	} else {
+		var first byte
+		first, err = peekFirstByte(reader)
+		switch {
+		case err != nil:
+			logger.Debug("Empty input")
+			err = nil
+		case first == '{':
+			payloads, err = ir.readJSON(logger, reader)
+		case ir.options.Strict:
+			return nil, fmt.Errorf("%w: expected a hook JSON payload, not plain text", ErrInvalidInput)
+		default:
+			payloads = ir.readPlainText(logger, reader)
+		}
+	}
+
+	if limited.err != nil {
+		if len(payloads) > 0 && payloads[0].Hook == nil {
+			// The last path may have been cut short, so none are trusted
+			return nil, limited.err
+		}
+		err = limited.err
+	}
+
+	for i := range payloads {
+		preparePayload(logger, &payloads[i])
+	}
+	return payloads, err
+}
+
+// readJSON decodes a stream of JSON payloads. Unless the reader is strict, input that
+// does not start with a JSON value, such as a truncated payload, is read as plain text paths.
+func (ir *inputReader) readJSON(logger logging.Logger, reader *bufio.Reader) ([]Payload, error) {
+	if !ir.options.Strict {
+		if err := checkJSONStart(reader); err != nil {
+			logger.Debug(fmt.Sprintf("Input is not hook JSON, reading it as plain text: %v", err))
+			return ir.readPlainText(logger, reader), nil
+		}
+	}
+	return ir.streamDecoder.decode(logger, reader, ir.options)
+}
+
+// readPathList reads an explicit list of file paths separated by newlines or NUL bytes
+func (ir *inputReader) readPathList(logger logging.Logger, reader io.Reader) []Payload {
+	if !ir.options.NullSeparated {
+		return ir.readPlainText(logger, reader)
+	}
+
+	data, _ := io.ReadAll(reader)
+	var paths []string
+	for path := range strings.SplitSeq(string(data), "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		logger.Debug("Empty input")
 		return nil
 	}
 
-	lines := readInputLines(input)
+	logger.Debug(fmt.Sprintf("Input received (%d NUL-separated paths)", len(paths)))
+	return []Payload{{Paths: paths}}
+}
 
+// readPlainText reads plain text input as a single payload of file paths
+func (ir *inputReader) readPlainText(logger logging.Logger, reader io.Reader) []Payload {
+	lines := readInputLines(reader)
 	if len(lines) == 0 {
 		logger.Debug("Empty input")
 		return nil
@@ -147,30 +238,107 @@ func (ir *inputReader) readPaths(logger logging.Logger, input io.Reader) []strin
 		logger.Debug(fmt.Sprintf("  Line %d: %s", i+1, line))
 	}
 
-	inputText := strings.Join(lines, "\n")
-	paths := ir.pathParser.parse(inputText)
+	paths := ir.pathParser.parsePlainText(strings.Join(lines, "\n"))
+	return []Payload{{Paths: paths}}
+}
+
+// preparePayload applies the hook metadata to a payload's paths and resolves them
+func preparePayload(logger logging.Logger, payload *Payload) {
+	hook := payload.Hook
+	cwd := ""
+	if hook != nil {
+		logHookInput(logger, hook)
+		payload.Paths = applyToolResponse(logger, hook, payload.Paths)
+		cwd = hook.Cwd
+	}
+	payload.Paths = newPathResolver(cwd).resolve(logger, payload.Paths)
 
-	if len(paths) > 0 {
-		if ir.pathParser.isJSON(inputText) {
+	if len(payload.Paths) > 0 {
+		if hook != nil {
 			logger.Debug("JSON parsing successful")
 		} else {
 			logger.Debug("Plain text parsing used")
 		}
 		logger.Debug("Extracted file paths:")
-		for i, path := range paths {
+		for i, path := range payload.Paths {
 			logger.Debug(fmt.Sprintf("  [%d] %s", i+1, path))
 		}
 	} else {
 		logger.Debug("No file paths found")
 	}
+}
+
+// logHookInput reports the hook metadata in debug output
+func logHookInput(logger logging.Logger, hook *HookInput) {
+	if hook.HookEventName != "" {
+		logger.Debug(fmt.Sprintf("Hook event: %s", hook.HookEventName))
+	}
+	if hook.ToolName != "" {
+		logger.Debug(fmt.Sprintf("Tool: %s", hook.ToolName))
+	}
+	if hook.Cwd != "" {
+		logger.Debug(fmt.Sprintf("Cwd: %s", hook.Cwd))
+	}
+}
+
+// applyToolResponse narrows the paths using tool_response: failed tool calls
+// yield no paths, and the file the tool reports writing takes precedence
+func applyToolResponse(logger logging.Logger, hook *HookInput, paths []string) []string {
+	resp := hook.Response()
+	if resp == nil {
+		return paths
+	}
+
+	if resp.Failed() {
+		reason := "success is false"
+		if resp.Error != "" {
+			reason = resp.Error
+		}
+		logger.Debug(fmt.Sprintf("Skipping: tool call failed (%s)", reason))
+		return nil
+	}
+
+	if resp.FilePath != "" {
+		logger.Debug(fmt.Sprintf("Using file path from tool_response: %s", resp.FilePath))
+		return []string{resp.FilePath}
+	}
 
 	return paths
 }
 
-// ReadToolInput reads JSON input from the given reader and extracts file paths from tool_input fields
-func ReadToolInput(logger logging.Logger, input io.Reader) []string {
+// ReadPayloads reads the hook payloads in input, which may be a single JSON payload,
+// a stream of newline-delimited JSON payloads or, unless options are strict, plain
+// text file paths. Each payload's paths are resolved against its own cwd.
+// Input larger than the maximum size fails with ErrInputTooLarge. Input that is not
+// valid hook JSON fails with ErrInvalidInput in strict mode; otherwise only a payload
+// stream that breaks after its first payload, or a payload cut short after its first
+// 64 KiB, does.
+func ReadPayloads(logger logging.Logger, input io.Reader, options Options) ([]Payload, error) {
 	reader := newInputReader()
-	return reader.readPaths(logger, input)
+	reader.options = options
+	return reader.readPayloads(logger, input)
+}
+
+// ReadToolInput reads input with ReadPayloads and no size limit, returning the paths
+// it names. For a single JSON payload the decoded hook payload is returned as well;
+// it is nil for plain text input and payload streams, whose paths are combined.
+func ReadToolInput(logger logging.Logger, input io.Reader) ([]string, *HookInput) {
+	payloads, err := ReadPayloads(logger, input, Options{})
+	if err != nil {
+		logger.Debug(fmt.Sprintf("Error reading input: %v", err))
+	}
+	switch len(payloads) {
+	case 0:
+		return nil, nil
+	case 1:
+		return payloads[0].Paths, payloads[0].Hook
+	}
+
+	var paths []string
+	for _, payload := range payloads {
+		paths = append(paths, payload.Paths...)
+	}
+	return newPathResolver("").resolve(logger, paths), nil
 }
 
 // ParseToolInput parses tool input JSON and extracts file paths
@@ -184,13 +352,20 @@ func ParseToolInput(jsonText string) ([]string, error) {
 	return paths, nil
 }
 
-// readInputLines reads and normalizes input lines, trimming empty lines at start and end
+// readInputLines reads and normalizes input lines, trimming empty lines at start and end.
+// Lines may be of any length.
 func readInputLines(input io.Reader) []string {
 	var lines []string
-	scanner := bufio.NewScanner(input)
+	reader := bufio.NewReader(input)
 
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			lines = append(lines, strings.TrimRight(line, "\r\n"))
+		}
+		if err != nil {
+			break
+		}
 	}
 
 	// Trim empty lines from start and end
@@ -1,8 +1,11 @@
 package toolinput
 
 import (
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
 )
 
 func TestParseToolInput(t *testing.T) {
@@ -41,6 +44,11 @@ func TestParseToolInput(t *testing.T) {
 			input:    `{"tool_input": {"paths": ["/test/file1.txt", "/test/file2.go"]}}`,
 			expected: []string{"/test/file1.txt", "/test/file2.go"},
 		},
+		{
+			name:     "NotebookEdit tool with notebook_path field",
+			input:    `{"tool_input": {"notebook_path": "/test/analysis.ipynb", "new_source": "x = 1"}}`,
+			expected: []string{"/test/analysis.ipynb"},
+		},
 		{
 			name:     "Multiple fields",
 			input:    `{"tool_input": {"path": "/test/file1.txt", "file_path": "/test/file2.go"}}`,
@@ -248,6 +256,8 @@ func (m *mockLogger) ShowProcessingEnd(totalFiles, processedFiles int) {
 }
 
 func TestReadToolInput(t *testing.T) {
+	t.Setenv(cli.ProjectDirEnv, "")
+
 	tests := []struct {
 		name     string
 		input    string
@@ -273,6 +283,11 @@ func TestReadToolInput(t *testing.T) {
 			input:    "",
 			expected: []string{},
 		},
+		{
+			name:     "Whitespace only input",
+			input:    "   \n\t  ",
+			expected: []string{},
+		},
 		{
 			name:     "Invalid JSON",
 			input:    "not valid json",
@@ -285,7 +300,7 @@ func TestReadToolInput(t *testing.T) {
 			logger := &mockLogger{}
 			reader := strings.NewReader(tt.input)
 
-			result := ReadToolInput(logger, reader)
+			result, _ := ReadToolInput(logger, reader)
 
 			if len(result) != len(tt.expected) {
 				t.Errorf("ReadToolInput() length = %v, want %v", len(result), len(tt.expected))
@@ -300,51 +315,6 @@ func TestReadToolInput(t *testing.T) {
 	}
 }
 
-func TestPathExtractorIsJSON(t *testing.T) {
-	extractor := newPathExtractor()
-
-	tests := []struct {
-		name     string
-		input    string
-		expected bool
-	}{
-		{
-			name:     "valid JSON object",
-			input:    `{"tool_input": {"path": "/test/file.txt"}}`,
-			expected: true,
-		},
-		{
-			name:     "valid JSON array",
-			input:    `[1, 2, 3]`,
-			expected: false, // isJSON only checks for objects, not arrays
-		},
-		{
-			name:     "invalid JSON",
-			input:    "not json",
-			expected: false,
-		},
-		{
-			name:     "empty string",
-			input:    "",
-			expected: false,
-		},
-		{
-			name:     "malformed JSON",
-			input:    `{"tool_input": {"path": "/test/file.txt"}`,
-			expected: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := extractor.isJSON(tt.input)
-			if result != tt.expected {
-				t.Errorf("isJSON() = %v, want %v", result, tt.expected)
-			}
-		})
-	}
-}
-
 func TestPathExtractorExtractPathsFromToolInput(t *testing.T) {
 	extractor := newPathExtractor()
 
@@ -428,55 +398,6 @@ func TestInputCheckerCheckAvailability(t *testing.T) {
 	}
 }
 
-func TestInputReaderReadPaths(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected []string
-	}{
-		{
-			name:     "JSON input",
-			input:    `{"tool_input": {"file_path": "/test/file.txt"}}`,
-			expected: []string{"/test/file.txt"},
-		},
-		{
-			name:     "plain text input",
-			input:    "/test/file1.txt\n/test/file2.go",
-			expected: []string{"/test/file1.txt", "/test/file2.go"},
-		},
-		{
-			name:     "empty input",
-			input:    "",
-			expected: []string{},
-		},
-		{
-			name:     "whitespace only input",
-			input:    "   \n\t  ",
-			expected: []string{},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			logger := &mockLogger{}
-			reader := newInputReader()
-			inputReader := strings.NewReader(tt.input)
-
-			result := reader.readPaths(logger, inputReader)
-
-			if len(result) != len(tt.expected) {
-				t.Errorf("readPaths() length = %v, want %v", len(result), len(tt.expected))
-				return
-			}
-			for i, path := range result {
-				if path != tt.expected[i] {
-					t.Errorf("readPaths()[%d] = %v, want %v", i, path, tt.expected[i])
-				}
-			}
-		})
-	}
-}
-
 func TestParseToolInputEdgeCases(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -529,3 +450,177 @@ func TestParseToolInputEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestReadPayloads(t *testing.T) {
+	t.Setenv(cli.ProjectDirEnv, "")
+
+	tests := []struct {
+		name   string
+		input  string
+		expect []Payload
+	}{
+		{
+			name:   "empty input",
+			input:  "",
+			expect: nil,
+		},
+		{
+			name:   "plain text",
+			input:  "/a/file.txt\n/b/file.txt",
+			expect: []Payload{{Paths: []string{"/a/file.txt", "/b/file.txt"}}},
+		},
+		{
+			name:   "single payload",
+			input:  `{"tool_name": "Write", "cwd": "/a", "tool_input": {"file_path": "file.txt"}}`,
+			expect: []Payload{{Hook: &HookInput{ToolName: "Write"}, Paths: []string{"/a/file.txt"}}},
+		},
+		{
+			name: "payload stream with own cwd and tool",
+			input: `{"tool_name": "Write", "cwd": "/a", "tool_input": {"file_path": "file.txt"}}` + "\n" +
+				`{"tool_name": "Read", "cwd": "/b", "tool_input": {"file_path": "file.txt"}}` + "\n" +
+				`{"tool_name": "Edit", "cwd": "/c", "tool_input": {"file_path": "file.txt"}, "tool_response": {"success": false}}`,
+			expect: []Payload{
+				{Hook: &HookInput{ToolName: "Write"}, Paths: []string{"/a/file.txt"}},
+				{Hook: &HookInput{ToolName: "Read"}, Paths: []string{"/b/file.txt"}},
+				{Hook: &HookInput{ToolName: "Edit"}, Paths: nil},
+			},
+		},
+		{
+			name: "invalid payload in stream skipped",
+			input: `{"tool_name": "Write", "tool_input": {"file_path": "/a/file.txt"}}` + "\n" +
+				`{"tool_name": 5}`,
+			expect: []Payload{{Hook: &HookInput{ToolName: "Write"}, Paths: []string{"/a/file.txt"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads, err := ReadPayloads(&mockLogger{}, strings.NewReader(tt.input), Options{})
+			if err != nil {
+				t.Fatalf("ReadPayloads() error = %v", err)
+			}
+			if len(payloads) != len(tt.expect) {
+				t.Fatalf("ReadPayloads() returned %d payloads, want %d", len(payloads), len(tt.expect))
+			}
+			for i, payload := range payloads {
+				expected := tt.expect[i]
+				if (payload.Hook == nil) != (expected.Hook == nil) {
+					t.Fatalf("payload %d hook = %v, want %v", i, payload.Hook, expected.Hook)
+				}
+				if payload.Hook != nil && payload.Hook.ToolName != expected.Hook.ToolName {
+					t.Errorf("payload %d ToolName = %v, want %v", i, payload.Hook.ToolName, expected.Hook.ToolName)
+				}
+				if strings.Join(payload.Paths, ",") != strings.Join(expected.Paths, ",") {
+					t.Errorf("payload %d Paths = %v, want %v", i, payload.Paths, expected.Paths)
+				}
+			}
+		})
+	}
+}
+
+func TestReadPayloadsModes(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		options     Options
+		expectErr   error
+		expectPaths []string
+		expectHook  bool
+	}{
+		{
+			name:        "auto mode falls back to plain text",
+			input:       "/test/file1.txt\n/test/file2.txt",
+			options:     Options{},
+			expectPaths: []string{"/test/file1.txt", "/test/file2.txt"},
+		},
+		{
+			name:        "strict mode accepts hook JSON",
+			input:       `{"tool_name": "Write", "tool_input": {"file_path": "/test/file.txt"}}`,
+			options:     Options{Strict: true},
+			expectPaths: []string{"/test/file.txt"},
+			expectHook:  true,
+		},
+		{
+			name:      "strict mode rejects plain text",
+			input:     "/test/file1.txt\n/test/file2.txt",
+			options:   Options{Strict: true},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:        "auto mode reads truncated payload as plain text",
+			input:       "{odd}.txt\n/test/file.txt",
+			options:     Options{},
+			expectPaths: []string{"/{odd}.txt", "/test/file.txt"},
+		},
+		{
+			name:      "auto mode rejects payload truncated past the peeked input",
+			input:     `{"tool_name": "Write", "tool_input": {"content": "` + strings.Repeat("x", jsonPeekSize),
+			options:   Options{},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:      "auto mode rejects stream that breaks after a payload",
+			input:     `{"tool_name": "Write"}` + "\n" + `{"tool_name": "Wri`,
+			options:   Options{},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:      "strict mode rejects truncated payload",
+			input:     `{"tool_name": "Write", "tool_input": {"file_pa`,
+			options:   Options{Strict: true},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:      "strict mode rejects payload of the wrong shape",
+			input:     `{"tool_name": 5}`,
+			options:   Options{Strict: true},
+			expectErr: ErrInvalidInput,
+		},
+		{
+			name:    "strict mode accepts empty input",
+			input:   "",
+			options: Options{Strict: true},
+		},
+		{
+			name:        "paths mode does not parse JSON",
+			input:       "{odd}.txt\n/test/file.txt\n",
+			options:     Options{PathsOnly: true, Strict: true},
+			expectPaths: []string{"/{odd}.txt", "/test/file.txt"},
+		},
+		{
+			name:        "NUL-separated paths keep spaces and newlines",
+			input:       "/test/with space.txt\x00/test/new\nline.txt\x00",
+			options:     Options{PathsOnly: true, NullSeparated: true},
+			expectPaths: []string{"/test/with space.txt", "/test/new\nline.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Relative plain text paths resolve against the root for a stable result
+			t.Setenv(cli.ProjectDirEnv, "/")
+
+			payloads, err := ReadPayloads(&mockLogger{}, strings.NewReader(tt.input), tt.options)
+			if tt.expectErr != nil {
+				if !errors.Is(err, tt.expectErr) {
+					t.Fatalf("ReadPayloads() error = %v, want %v", err, tt.expectErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadPayloads() error = %v", err)
+			}
+
+			var paths []string
+			for _, payload := range payloads {
+				paths = append(paths, payload.Paths...)
+				if (payload.Hook != nil) != tt.expectHook {
+					t.Errorf("Hook = %v, expectHook %v", payload.Hook, tt.expectHook)
+				}
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.expectPaths, ",") {
+				t.Errorf("paths = %q, want %q", paths, tt.expectPaths)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package toolinput
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+	"github.com/koh-sh/ccnewline/internal/logging"
+)
+
+// pathResolver turns tool paths into canonical absolute paths
+type pathResolver struct {
+	baseDir string
+}
+
+// newPathResolver creates a resolver for relative paths, using the hook's cwd
+// and falling back to CLAUDE_PROJECT_DIR when the payload carries none
+func newPathResolver(cwd string) *pathResolver {
+	if cwd == "" {
+		cwd = os.Getenv(cli.ProjectDirEnv)
+	}
+	return &pathResolver{baseDir: cwd}
+}
+
+// resolve canonicalizes paths and drops duplicates, keeping the first occurrence
+func (pr *pathResolver) resolve(logger logging.Logger, paths []string) []string {
+	var result []string
+	seen := make(map[string]bool, len(paths))
+
+	for _, path := range paths {
+		resolved := pr.canonicalize(path)
+		if resolved != path {
+			logger.Debug(fmt.Sprintf("Resolved %s -> %s", path, resolved))
+		}
+		if seen[resolved] {
+			logger.Debug(fmt.Sprintf("Skipping duplicate path %s", resolved))
+			continue
+		}
+		seen[resolved] = true
+		result = append(result, resolved)
+	}
+
+	return result
+}
+
+// canonicalize makes path absolute against the base directory, cleans it and evaluates symlinks
+func (pr *pathResolver) canonicalize(path string) string {
+	if !filepath.IsAbs(path) && pr.baseDir != "" {
+		path = filepath.Join(pr.baseDir, path)
+	}
+	cleaned := filepath.Clean(path)
+
+	if resolved, err := filepath.EvalSymlinks(cleaned); err == nil {
+		return resolved
+	}
+
+	// The file itself may not exist yet; still resolve symlinks in its directory
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(cleaned)); err == nil {
+		return filepath.Join(dir, filepath.Base(cleaned))
+	}
+
+	return cleaned
+}
@@ -0,0 +1,123 @@
+package toolinput
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+func TestPathResolverCanonicalize(t *testing.T) {
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	realDir := filepath.Join(tempDir, "real")
+	linkDir := filepath.Join(tempDir, "link")
+	_ = os.Mkdir(realDir, 0o755)
+	_ = os.WriteFile(filepath.Join(realDir, "file.txt"), []byte("content"), 0o644)
+	if err := os.Symlink(realDir, linkDir); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		baseDir  string
+		path     string
+		expected string
+	}{
+		{
+			name:     "absolute path",
+			baseDir:  "/other",
+			path:     filepath.Join(realDir, "file.txt"),
+			expected: filepath.Join(realDir, "file.txt"),
+		},
+		{
+			name:     "relative path joined with base",
+			baseDir:  realDir,
+			path:     "file.txt",
+			expected: filepath.Join(realDir, "file.txt"),
+		},
+		{
+			name:     "unclean path",
+			baseDir:  tempDir,
+			path:     "real/../real/./file.txt",
+			expected: filepath.Join(realDir, "file.txt"),
+		},
+		{
+			name:     "symlinked directory",
+			baseDir:  tempDir,
+			path:     "link/file.txt",
+			expected: filepath.Join(realDir, "file.txt"),
+		},
+		{
+			name:     "missing file in symlinked directory",
+			baseDir:  tempDir,
+			path:     "link/missing.txt",
+			expected: filepath.Join(realDir, "missing.txt"),
+		},
+		{
+			name:     "relative path without base",
+			baseDir:  "",
+			path:     "dir/../file.txt",
+			expected: "file.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &pathResolver{baseDir: tt.baseDir}
+			result := resolver.canonicalize(tt.path)
+			if result != tt.expected {
+				t.Errorf("canonicalize() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPathResolverResolveDeduplicates(t *testing.T) {
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(tempDir, "file.txt")
+	_ = os.WriteFile(file, []byte("content"), 0o644)
+
+	logger := &mockLogger{}
+	resolver := &pathResolver{baseDir: tempDir}
+	result := resolver.resolve(logger, []string{"file.txt", file, "./file.txt"})
+
+	if len(result) != 1 || result[0] != file {
+		t.Errorf("resolve() = %v, want [%s]", result, file)
+	}
+}
+
+func TestNewPathResolverFallback(t *testing.T) {
+	t.Setenv(cli.ProjectDirEnv, "/project")
+
+	if resolver := newPathResolver("/session"); resolver.baseDir != "/session" {
+		t.Errorf("baseDir = %v, want /session", resolver.baseDir)
+	}
+	if resolver := newPathResolver(""); resolver.baseDir != "/project" {
+		t.Errorf("baseDir = %v, want /project", resolver.baseDir)
+	}
+}
+
+func TestReadToolInputResolvesAgainstCwd(t *testing.T) {
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(tempDir, "file.txt")
+	_ = os.WriteFile(file, []byte("content"), 0o644)
+
+	logger := &mockLogger{}
+	input := `{"cwd": "` + tempDir + `", "tool_input": {"path": "file.txt", "file_path": "` + file + `"}}`
+	paths, _ := ReadToolInput(logger, strings.NewReader(input))
+
+	if len(paths) != 1 || paths[0] != file {
+		t.Errorf("ReadToolInput() = %v, want [%s]", paths, file)
+	}
+}
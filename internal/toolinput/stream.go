@@ -0,0 +1,169 @@
+package toolinput
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/koh-sh/ccnewline/internal/logging"
+)
+
+// Input errors reported by ReadPayloads
+var (
+	// ErrInputTooLarge is returned when the input exceeds the configured maximum size
+	ErrInputTooLarge = errors.New("input exceeds the maximum size")
+	// ErrInvalidInput is returned when the input is not a valid hook payload
+	ErrInvalidInput = errors.New("invalid hook input")
+)
+
+// limitedReader fails with ErrInputTooLarge once more than limit bytes are read.
+// A limit of zero or less reads without a limit.
+type limitedReader struct {
+	reader    io.Reader
+	limit     int64
+	remaining int64
+	err       error
+}
+
+// newLimitedReader creates a reader that allows at most limit bytes of input
+func newLimitedReader(reader io.Reader, limit int64) *limitedReader {
+	return &limitedReader{reader: reader, limit: limit, remaining: limit}
+}
+
+// Read reads from the underlying reader, failing when the limit is exceeded
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.err != nil {
+		return 0, lr.err
+	}
+	if lr.limit <= 0 {
+		return lr.reader.Read(p)
+	}
+
+	// Read one byte past the limit to tell input of exactly limit bytes from larger input
+	if int64(len(p)) > lr.remaining+1 {
+		p = p[:lr.remaining+1]
+	}
+	n, err := lr.reader.Read(p)
+	if int64(n) <= lr.remaining {
+		lr.remaining -= int64(n)
+		return n, err
+	}
+
+	n = int(lr.remaining)
+	lr.remaining = 0
+	lr.err = fmt.Errorf("%w of %d bytes", ErrInputTooLarge, lr.limit)
+	return n, lr.err
+}
+
+// jsonPeekSize is how much input is examined, without consuming it, to tell hook JSON
+// from plain text paths that start with '{'
+const jsonPeekSize = 64 * 1024
+
+// toolInputPaths holds the path fields of tool_input. Decoding the raw tool_input into
+// it builds only these fields; the raw arguments, such as the content of a Write call,
+// are kept by HookInput until the payload is discarded or Options.DiscardToolInput
+// releases them.
+type toolInputPaths struct {
+	Path         any `json:"path"`
+	FilePath     any `json:"file_path"`
+	NotebookPath any `json:"notebook_path"`
+	Paths        any `json:"paths"`
+}
+
+// asMap returns the fields present in the tool input, keyed by their JSON names
+func (tip *toolInputPaths) asMap() map[string]any {
+	fields := make(map[string]any)
+	for name, value := range map[string]any{
+		"path":          tip.Path,
+		"file_path":     tip.FilePath,
+		"notebook_path": tip.NotebookPath,
+		"paths":         tip.Paths,
+	} {
+		if value != nil {
+			fields[name] = value
+		}
+	}
+	return fields
+}
+
+// streamDecoder decodes hook payloads from a stream without splitting it into lines,
+// so payloads embedding large file contents on a single line are read in full
+type streamDecoder struct {
+	extractor *pathExtractor
+}
+
+// newStreamDecoder creates a new stream decoder
+func newStreamDecoder() *streamDecoder {
+	return &streamDecoder{extractor: newPathExtractor()}
+}
+
+// decode reads one or more concatenated or newline-delimited JSON payloads.
+// Malformed JSON ends the stream with an error. Payloads of the wrong shape are
+// skipped, or end the stream with an error when strict.
+func (sd *streamDecoder) decode(logger logging.Logger, reader io.Reader, options Options) ([]Payload, error) {
+	strict := options.Strict
+	var payloads []Payload
+	decoder := json.NewDecoder(reader)
+
+	for i := 1; ; i++ {
+		var hook HookInput
+		err := decoder.Decode(&hook)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && !strict {
+			logger.Debug(fmt.Sprintf("Skipping payload %d: %v", i, err))
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, ErrInputTooLarge) {
+				return payloads, err
+			}
+			return payloads, fmt.Errorf("%w: payload %d: %w", ErrInvalidInput, i, err)
+		}
+		paths := sd.extractor.pathsFromHook(&hook)
+		if options.DiscardToolInput {
+			hook.ToolInput = nil
+		}
+		payloads = append(payloads, Payload{Hook: &hook, Paths: paths})
+	}
+
+	if len(payloads) > 1 {
+		logger.Debug(fmt.Sprintf("Detected stream of %d JSON payloads", len(payloads)))
+	}
+	return payloads, nil
+}
+
+// peekFirstByte returns the first non-whitespace byte of the input without consuming it
+func peekFirstByte(reader *bufio.Reader) (byte, error) {
+	for {
+		next, err := reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch next[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = reader.ReadByte()
+		default:
+			return next[0], nil
+		}
+	}
+}
+
+// checkJSONStart reports why the input does not start with a JSON value, peeking at
+// up to jsonPeekSize bytes without consuming them. A value that runs past the peeked
+// bytes is taken to be JSON, so a payload cut short after them fails to decode
+// instead of being read as plain text.
+func checkJSONStart(reader *bufio.Reader) error {
+	head, _ := reader.Peek(min(jsonPeekSize, reader.Size()))
+	var value json.RawMessage
+	err := json.NewDecoder(bytes.NewReader(head)).Decode(&value)
+	if errors.Is(err, io.ErrUnexpectedEOF) && len(head) == reader.Size() {
+		return nil
+	}
+	return err
+}
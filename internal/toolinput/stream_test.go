@@ -0,0 +1,193 @@
+package toolinput
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/koh-sh/ccnewline/internal/cli"
+)
+
+func TestLimitedReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		limit     int64
+		expectErr bool
+	}{
+		{name: "no limit", input: "0123456789", limit: 0, expectErr: false},
+		{name: "under limit", input: "0123456789", limit: 20, expectErr: false},
+		{name: "exactly at limit", input: "0123456789", limit: 10, expectErr: false},
+		{name: "over limit", input: "0123456789", limit: 9, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := io.ReadAll(newLimitedReader(strings.NewReader(tt.input), tt.limit))
+			if errors.Is(err, ErrInputTooLarge) != tt.expectErr {
+				t.Fatalf("ReadAll() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !tt.expectErr && string(data) != tt.input {
+				t.Errorf("ReadAll() = %q, want %q", data, tt.input)
+			}
+			if tt.expectErr && int64(len(data)) > tt.limit {
+				t.Errorf("Read %d bytes past the limit of %d", len(data), tt.limit)
+			}
+		})
+	}
+}
+
+func TestStreamDecoderDecode(t *testing.T) {
+	decoder := newStreamDecoder()
+
+	tests := []struct {
+		name        string
+		input       string
+		expectCount int
+		expectErr   bool
+	}{
+		{name: "single object", input: `{"tool_input": {"file_path": "/a"}}`, expectCount: 1},
+		{name: "newline-delimited objects", input: "{\"cwd\": \"/a\"}\n{\"cwd\": \"/b\"}\n{\"cwd\": \"/c\"}\n", expectCount: 3},
+		{name: "pretty-printed objects", input: "{\n  \"cwd\": \"/a\"\n}\n{\n  \"cwd\": \"/b\"\n}", expectCount: 2},
+		{name: "wrong field type skipped", input: "{\"cwd\": 5}\n{\"cwd\": \"/b\"}", expectCount: 1},
+		{name: "malformed JSON", input: `{"cwd": "/a"`, expectCount: 0, expectErr: true},
+		{name: "object followed by text", input: "{\"cwd\": \"/a\"}\n/test/file.txt", expectCount: 1, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads, err := decoder.decode(&mockLogger{}, strings.NewReader(tt.input), Options{})
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("decode() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if len(payloads) != tt.expectCount {
+				t.Errorf("decode() returned %d payloads, want %d", len(payloads), tt.expectCount)
+			}
+		})
+	}
+}
+
+func TestStreamDecoderDiscardToolInput(t *testing.T) {
+	input := `{"tool_name": "Write", "tool_input": {"file_path": "/a.go", "content": "package main"}}`
+
+	tests := []struct {
+		name       string
+		options    Options
+		expectKept bool
+	}{
+		{name: "kept by default", options: Options{}, expectKept: true},
+		{name: "discarded", options: Options{DiscardToolInput: true}, expectKept: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads, err := newStreamDecoder().decode(&mockLogger{}, strings.NewReader(input), tt.options)
+			if err != nil || len(payloads) != 1 {
+				t.Fatalf("decode() = %v, %v, want one payload", payloads, err)
+			}
+			if strings.Join(payloads[0].Paths, ",") != "/a.go" {
+				t.Errorf("Paths = %v, want [/a.go]", payloads[0].Paths)
+			}
+			if kept := payloads[0].Hook.ToolInput != nil; kept != tt.expectKept {
+				t.Errorf("ToolInput kept = %v, want %v", kept, tt.expectKept)
+			}
+		})
+	}
+}
+
+func TestPathExtractorPathsFromHook(t *testing.T) {
+	extractor := newPathExtractor()
+
+	tests := []struct {
+		name      string
+		toolInput string
+		expected  []string
+	}{
+		{name: "file path", toolInput: `{"file_path": "/a.go", "content": "package main"}`, expected: []string{"/a.go"}},
+		{name: "notebook path", toolInput: `{"notebook_path": "/nb.ipynb", "new_source": "x"}`, expected: []string{"/nb.ipynb"}},
+		{name: "paths array", toolInput: `{"paths": ["/a", 1, "/b"]}`, expected: []string{"/a", "/b"}},
+		{name: "path of wrong type", toolInput: `{"file_path": 5}`, expected: nil},
+		{name: "not an object", toolInput: `["/a"]`, expected: nil},
+		{name: "missing", toolInput: ``, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := &HookInput{ToolInput: []byte(tt.toolInput)}
+			paths := extractor.pathsFromHook(hook)
+			if strings.Join(paths, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("pathsFromHook() = %v, want %v", paths, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadPayloadsLargePayload(t *testing.T) {
+	t.Setenv(cli.ProjectDirEnv, "")
+
+	// A Write payload embedding a large file on a single line
+	content := strings.Repeat("x", 1<<20)
+	input := `{"tool_name": "Write", "tool_input": {"file_path": "/test/big.txt", "content": "` + content + `"}}`
+
+	payloads, err := ReadPayloads(&mockLogger{}, strings.NewReader(input), Options{})
+	if err != nil {
+		t.Fatalf("ReadPayloads() error = %v", err)
+	}
+	if len(payloads) != 1 || strings.Join(payloads[0].Paths, ",") != "/test/big.txt" {
+		t.Fatalf("ReadPayloads() = %v, want a payload for /test/big.txt", payloads)
+	}
+
+	in, err := payloads[0].Hook.WriteInput()
+	if err != nil || len(in.Content) != len(content) {
+		t.Errorf("Write content was not read in full")
+	}
+}
+
+func TestReadPayloadsMaxSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		maxSize     int64
+		expectErr   bool
+		expectCount int
+	}{
+		{
+			name:        "JSON under the limit",
+			input:       `{"tool_input": {"file_path": "/a"}}`,
+			maxSize:     1024,
+			expectCount: 1,
+		},
+		{
+			name:      "JSON over the limit",
+			input:     `{"tool_input": {"file_path": "/a", "content": "` + strings.Repeat("x", 100) + `"}}`,
+			maxSize:   64,
+			expectErr: true,
+		},
+		{
+			name:        "stream cut by the limit keeps earlier payloads",
+			input:       `{"tool_input": {"file_path": "/a"}}` + "\n" + `{"tool_input": {"file_path": "/b", "content": "` + strings.Repeat("x", 100) + `"}}`,
+			maxSize:     64,
+			expectErr:   true,
+			expectCount: 1,
+		},
+		{
+			name:      "plain text over the limit",
+			input:     strings.Repeat("/test/file.txt\n", 10),
+			maxSize:   64,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads, err := ReadPayloads(&mockLogger{}, strings.NewReader(tt.input), Options{MaxSize: tt.maxSize})
+			if errors.Is(err, ErrInputTooLarge) != tt.expectErr {
+				t.Fatalf("ReadPayloads() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if len(payloads) != tt.expectCount {
+				t.Errorf("ReadPayloads() returned %d payloads, want %d", len(payloads), tt.expectCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package toolinput
+
+// Claude Code tool names
+const (
+	ToolWrite        = "Write"
+	ToolEdit         = "Edit"
+	ToolMultiEdit    = "MultiEdit"
+	ToolNotebookEdit = "NotebookEdit"
+	ToolRead         = "Read"
+	ToolGlob         = "Glob"
+	ToolGrep         = "Grep"
+	ToolLS           = "LS"
+	ToolNotebookRead = "NotebookRead"
+	ToolWebFetch     = "WebFetch"
+	ToolWebSearch    = "WebSearch"
+)
+
+// ToolKind classifies tools by their effect on files
+type ToolKind int
+
+const (
+	// ToolKindUnknown is a tool ccnewline has no knowledge of, such as an MCP tool
+	ToolKindUnknown ToolKind = iota
+	// ToolKindMutating is a tool that writes files
+	ToolKindMutating
+	// ToolKindReadOnly is a tool that never writes files
+	ToolKindReadOnly
+)
+
+// toolKinds maps known tool names to their kind
+var toolKinds = map[string]ToolKind{
+	ToolWrite:        ToolKindMutating,
+	ToolEdit:         ToolKindMutating,
+	ToolMultiEdit:    ToolKindMutating,
+	ToolNotebookEdit: ToolKindMutating,
+	ToolRead:         ToolKindReadOnly,
+	ToolGlob:         ToolKindReadOnly,
+	ToolGrep:         ToolKindReadOnly,
+	ToolLS:           ToolKindReadOnly,
+	ToolNotebookRead: ToolKindReadOnly,
+	ToolWebFetch:     ToolKindReadOnly,
+	ToolWebSearch:    ToolKindReadOnly,
+}
+
+// ClassifyTool returns the kind of the named tool
+func ClassifyTool(name string) ToolKind {
+	return toolKinds[name]
+}
+
+// IsMutatingTool reports whether the named tool writes files
+func IsMutatingTool(name string) bool {
+	return ClassifyTool(name) == ToolKindMutating
+}
+
+// IsReadOnlyTool reports whether the named tool never writes files
+func IsReadOnlyTool(name string) bool {
+	return ClassifyTool(name) == ToolKindReadOnly
+}
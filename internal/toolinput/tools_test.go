@@ -0,0 +1,37 @@
+package toolinput
+
+import "testing"
+
+func TestClassifyTool(t *testing.T) {
+	tests := []struct {
+		name           string
+		tool           string
+		expected       ToolKind
+		expectMutating bool
+		expectReadOnly bool
+	}{
+		{name: "Write", tool: "Write", expected: ToolKindMutating, expectMutating: true},
+		{name: "Edit", tool: "Edit", expected: ToolKindMutating, expectMutating: true},
+		{name: "MultiEdit", tool: "MultiEdit", expected: ToolKindMutating, expectMutating: true},
+		{name: "NotebookEdit", tool: "NotebookEdit", expected: ToolKindMutating, expectMutating: true},
+		{name: "Read", tool: "Read", expected: ToolKindReadOnly, expectReadOnly: true},
+		{name: "Glob", tool: "Glob", expected: ToolKindReadOnly, expectReadOnly: true},
+		{name: "Grep", tool: "Grep", expected: ToolKindReadOnly, expectReadOnly: true},
+		{name: "MCP tool", tool: "mcp__fs__write_file", expected: ToolKindUnknown},
+		{name: "empty name", tool: "", expected: ToolKindUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind := ClassifyTool(tt.tool); kind != tt.expected {
+				t.Errorf("ClassifyTool() = %v, want %v", kind, tt.expected)
+			}
+			if IsMutatingTool(tt.tool) != tt.expectMutating {
+				t.Errorf("IsMutatingTool() = %v, want %v", IsMutatingTool(tt.tool), tt.expectMutating)
+			}
+			if IsReadOnlyTool(tt.tool) != tt.expectReadOnly {
+				t.Errorf("IsReadOnlyTool() = %v, want %v", IsReadOnlyTool(tt.tool), tt.expectReadOnly)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package toolinput
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/koh-sh/ccnewline/internal/logging"
+)
+
+// errNoTranscript is returned when a hook payload does not name a transcript
+var errNoTranscript = errors.New("hook input has no transcript_path")
+
+// transcriptEntry is a single line of a Claude Code session transcript
+type transcriptEntry struct {
+	// Cwd is the working directory of the session when the entry was recorded
+	Cwd string `json:"cwd"`
+	// IsSidechain marks entries written by subagents
+	IsSidechain bool `json:"isSidechain"`
+	// Message holds the content blocks of user and assistant entries
+	Message struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// contentBlock is an element of a transcript message's content
+type contentBlock struct {
+	// Type is "tool_use" for tool calls and "tool_result" for their results
+	Type string `json:"type"`
+	// ID identifies a tool call
+	ID string `json:"id"`
+	// Name is the tool name of a tool call
+	Name string `json:"name"`
+	// Input holds the arguments of a tool call
+	Input json.RawMessage `json:"input"`
+	// ToolUseID is the tool call a result belongs to
+	ToolUseID string `json:"tool_use_id"`
+	// IsError reports whether the tool call failed
+	IsError bool `json:"is_error"`
+}
+
+// transcriptWrite is a file-writing tool call recorded in a transcript
+type transcriptWrite struct {
+	id    string
+	paths []string
+}
+
+// transcriptReader collects the files written during a session
+type transcriptReader struct {
+	extractor *pathExtractor
+	accepts   func(toolName string) bool
+	cwd       string
+}
+
+// newTranscriptReader creates a transcript reader for the tools accepted by accepts.
+// cwd resolves relative paths of entries that do not record their own.
+func newTranscriptReader(cwd string, accepts func(toolName string) bool) *transcriptReader {
+	return &transcriptReader{
+		extractor: newPathExtractor(),
+		accepts:   accepts,
+		cwd:       cwd,
+	}
+}
+
+// read scans the transcript and returns the written paths in the order they were first
+// written. Calls whose result is an error are left out, and so are malformed lines.
+func (tr *transcriptReader) read(logger logging.Logger, input io.Reader) ([]string, error) {
+	var writes []transcriptWrite
+	failed := make(map[string]bool)
+
+	reader := bufio.NewReader(input)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var entry transcriptEntry
+			if jsonErr := json.Unmarshal(line, &entry); jsonErr != nil {
+				logger.Debug(fmt.Sprintf("Skipping malformed transcript line %d", lineNumber))
+			} else {
+				writes = append(writes, tr.collect(logger, &entry, failed)...)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var paths []string
+	for _, write := range writes {
+		if failed[write.id] {
+			logger.Debug(fmt.Sprintf("Skipping: tool call %s failed", write.id))
+			continue
+		}
+		paths = append(paths, write.paths...)
+	}
+	return paths, nil
+}
+
+// collect returns the accepted tool calls of an entry and records failed tool results
+func (tr *transcriptReader) collect(logger logging.Logger, entry *transcriptEntry, failed map[string]bool) []transcriptWrite {
+	// User prompts carry plain string content
+	var blocks []contentBlock
+	if json.Unmarshal(entry.Message.Content, &blocks) != nil {
+		return nil
+	}
+
+	cwd := entry.Cwd
+	if cwd == "" {
+		cwd = tr.cwd
+	}
+	resolver := newPathResolver(cwd)
+
+	var writes []transcriptWrite
+	for _, block := range blocks {
+		switch block.Type {
+		case "tool_result":
+			if block.IsError {
+				failed[block.ToolUseID] = true
+			}
+		case "tool_use":
+			if !IsMutatingTool(block.Name) || !tr.accepts(block.Name) {
+				continue
+			}
+			var toolInput map[string]any
+			if json.Unmarshal(block.Input, &toolInput) != nil {
+				continue
+			}
+			write := transcriptWrite{id: block.ID}
+			for _, path := range tr.extractor.extractPathsFromToolInput(toolInput) {
+				write.paths = append(write.paths, resolver.canonicalize(path))
+			}
+			if entry.IsSidechain {
+				logger.Debug(fmt.Sprintf("Found %s call in subagent: %v", block.Name, write.paths))
+			} else {
+				logger.Debug(fmt.Sprintf("Found %s call: %v", block.Name, write.paths))
+			}
+			writes = append(writes, write)
+		}
+	}
+	return writes
+}
+
+// ReadTranscript returns the canonical paths of every file written during the session
+// of a Stop or SubagentStop hook payload, including writes made by subagents.
+// Only tools that write files and are accepted by accepts are considered, and each
+// path is returned once.
+func ReadTranscript(logger logging.Logger, hook *HookInput, accepts func(toolName string) bool) ([]string, error) {
+	transcriptPath := hook.Transcript()
+	if transcriptPath == "" {
+		return nil, errNoTranscript
+	}
+
+	file, err := os.Open(transcriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer file.Close()
+
+	logger.Debug(fmt.Sprintf("Reading transcript %s", transcriptPath))
+	paths, err := newTranscriptReader(hook.Cwd, accepts).read(logger, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return newPathResolver(hook.Cwd).resolve(logger, paths), nil
+}
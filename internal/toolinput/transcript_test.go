@@ -0,0 +1,147 @@
+package toolinput
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func acceptAll(string) bool { return true }
+
+func TestTranscriptReaderRead(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		expected []string
+	}{
+		{
+			name: "write edit and notebook edit",
+			lines: []string{
+				`{"type":"user","message":{"role":"user","content":"add a file"}}`,
+				`{"type":"assistant","message":{"content":[{"type":"text","text":"ok"},{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"/p/a.go","content":"x"}}]}}`,
+				`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"done"}]}}`,
+				`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"MultiEdit","input":{"file_path":"/p/b.go","edits":[]}},{"type":"tool_use","id":"t3","name":"NotebookEdit","input":{"notebook_path":"/p/c.ipynb"}}]}}`,
+			},
+			expected: []string{"/p/a.go", "/p/b.go", "/p/c.ipynb"},
+		},
+		{
+			name: "read-only and unknown tools ignored",
+			lines: []string{
+				`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"/p/a.go"}}]}}`,
+				`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Bash","input":{"command":"touch x"}}]}}`,
+			},
+			expected: nil,
+		},
+		{
+			name: "failed calls skipped",
+			lines: []string{
+				`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Edit","input":{"file_path":"/p/a.go"}}]}}`,
+				`{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"t1","is_error":true,"content":"String not found"}]}}`,
+				`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Edit","input":{"file_path":"/p/b.go"}}]}}`,
+			},
+			expected: []string{"/p/b.go"},
+		},
+		{
+			name: "subagent sidechain included",
+			lines: []string{
+				`{"type":"assistant","isSidechain":true,"message":{"content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"/p/agent.go"}}]}}`,
+			},
+			expected: []string{"/p/agent.go"},
+		},
+		{
+			name: "relative paths use entry cwd",
+			lines: []string{
+				`{"type":"assistant","cwd":"/p/sub","message":{"content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"a.go"}}]}}`,
+				`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Write","input":{"file_path":"b.go"}}]}}`,
+			},
+			expected: []string{"/p/sub/a.go", "/base/b.go"},
+		},
+		{
+			name: "malformed lines skipped",
+			lines: []string{
+				`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"/p/a.go"}}]}}`,
+				`{not json`,
+				`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Write","input":{"file_path":"/p/b.go"}}]}}`,
+			},
+			expected: []string{"/p/a.go", "/p/b.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := newTranscriptReader("/base", acceptAll)
+			paths, err := reader.read(&mockLogger{}, strings.NewReader(strings.Join(tt.lines, "\n")))
+			if err != nil {
+				t.Fatalf("read() error = %v", err)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("read() = %v, want %v", paths, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadTranscript(t *testing.T) {
+	tempDir := t.TempDir()
+	transcript := filepath.Join(tempDir, "session.jsonl")
+	agentTranscript := filepath.Join(tempDir, "agent.jsonl")
+	_ = os.WriteFile(transcript, []byte(strings.Join([]string{
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"a.go"}}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t2","name":"Edit","input":{"file_path":"./a.go"}}]}}`,
+		`{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t3","name":"Edit","input":{"file_path":"b.go"}}]}}`,
+	}, "\n")+"\n"), 0o644)
+	_ = os.WriteFile(agentTranscript, []byte(
+		`{"type":"assistant","isSidechain":true,"message":{"content":[{"type":"tool_use","id":"t1","name":"Write","input":{"file_path":"agent.go"}}]}}`+"\n"), 0o644)
+
+	tests := []struct {
+		name      string
+		hook      *HookInput
+		accepts   func(string) bool
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "session transcript deduplicated",
+			hook:     &HookInput{Cwd: tempDir, TranscriptPath: transcript},
+			accepts:  acceptAll,
+			expected: []string{filepath.Join(tempDir, "a.go"), filepath.Join(tempDir, "b.go")},
+		},
+		{
+			name:     "tools filtered",
+			hook:     &HookInput{Cwd: tempDir, TranscriptPath: transcript},
+			accepts:  func(name string) bool { return name == ToolEdit },
+			expected: []string{filepath.Join(tempDir, "a.go"), filepath.Join(tempDir, "b.go")},
+		},
+		{
+			name:     "subagent transcript preferred",
+			hook:     &HookInput{Cwd: tempDir, TranscriptPath: transcript, AgentTranscriptPath: agentTranscript},
+			accepts:  acceptAll,
+			expected: []string{filepath.Join(tempDir, "agent.go")},
+		},
+		{
+			name:      "no transcript path",
+			hook:      &HookInput{Cwd: tempDir},
+			accepts:   acceptAll,
+			expectErr: true,
+		},
+		{
+			name:      "missing transcript",
+			hook:      &HookInput{TranscriptPath: filepath.Join(tempDir, "missing.jsonl")},
+			accepts:   acceptAll,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := ReadTranscript(&mockLogger{}, tt.hook, tt.accepts)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("ReadTranscript() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("ReadTranscript() = %v, want %v", paths, tt.expected)
+			}
+		})
+	}
+}
@@ -22,5 +22,5 @@ var (
 func main() {
 	config := cli.ParseFlags(version, commit, date)
 	logger := logging.NewConsoleLogger(config)
-	processing.Run(config, logger, os.Stdin)
+	os.Exit(processing.Run(config, logger, os.Stdin))
 }